@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(ageCmd)
+
+	ageCmd.Flags().StringVar(&ageOlderThan, "older-than", "90d", "Report secrets last written longer ago "+
+		"than this, e.g. 90d or 12w")
+	ageCmd.Flags().StringVar(&ageOwnerKey, "owner-key", "owner", "custom_metadata key holding the owner of "+
+		"a secret, to group secrets by")
+	ageCmd.Flags().BoolVar(&ageCSV, "csv", false, "Enable CSV output")
+}
+
+var (
+	ageCSV       bool
+	ageOlderThan string
+	ageOwnerKey  string
+)
+
+var ageCmd = &cobra.Command{
+	Use:   "age [flags] [search-path]",
+	Short: "Report KV v2 secrets not rotated for a while",
+	Long: `Report KV v2 secrets not rotated for a while, grouped by mount and owner
+
+The age of a secret is the time since its current version was created, read
+from its metadata, never from its values. Secrets older than --older-than are
+listed by mount and by the owner stored in the custom_metadata key given with
+--owner-key, with the number of secrets checked in each group. If no
+search-path is given, all available KV v2 stores are crawled`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if ageCSV && jsonOutput {
+			return errors.New("--csv and --json are mutually exclusive")
+		}
+		_, err := parseDuration(ageOlderThan)
+		return err
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var searchPath string
+		if len(args) == 1 {
+			searchPath = args[0]
+		}
+		VaultAge(searchPath, AgeOptions{
+			CrawlingDelay: crawlingDelay,
+			CSVOutput:     ageCSV,
+			JSONOutput:    jsonOutput,
+			KvVersion:     kvVersion,
+			OlderThan:     ageOlderThan,
+			OwnerKey:      ageOwnerKey,
+			Timeout:       timeout,
+		})
+	},
+	Example: "vault-kv-search age --older-than 90d --owner-key team --csv secret/ > stale-secrets.csv",
+}
+
+// AgeOptions holds the settings of a secret age report
+type AgeOptions struct {
+	CrawlingDelay int
+	CSVOutput     bool
+	JSONOutput    bool
+	KvVersion     int
+	// OlderThan only reports secrets last written longer ago than this, like 90d
+	OlderThan string
+	// OwnerKey is the custom_metadata key holding the owner of secrets
+	OwnerKey string
+	Timeout  int
+}
+
+type secretAge struct {
+	Search   string `json:"search"`
+	FullPath string `json:"path"`
+	Mount    string `json:"mount"`
+	Owner    string `json:"owner"`
+	Updated  string `json:"updated"`
+	AgeDays  int    `json:"age_days"`
+}
+
+// ageGroup counts the secrets of an owner in a mount
+type ageGroup struct {
+	checked int
+	stale   []secretAge
+}
+
+// ageReporter collects the secrets older than a threshold
+type ageReporter struct {
+	now       time.Time
+	olderThan time.Duration
+	ownerKey  string
+
+	mu      sync.Mutex
+	checked int
+	// groups maps mounts to owners to their secrets
+	groups map[string]map[string]*ageGroup
+}
+
+// VaultAge reports the KV v2 secrets below searchPath, or of all KV v2
+// stores if it is empty, whose current version is older than a threshold
+func VaultAge(searchPath string, opts AgeOptions) {
+	client := newVaultClient(opts.Timeout)
+
+	olderThan, err := parseDuration(opts.OlderThan)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(exitStatusError)
+	}
+	reporter := &ageReporter{
+		now:       time.Now(),
+		olderThan: olderThan,
+		ownerKey:  opts.OwnerKey,
+		groups:    map[string]map[string]*ageGroup{},
+	}
+
+	vc := vaultClient{
+		crawlingDelay: opts.CrawlingDelay,
+		// Like JSON, CSV output can't have banners
+		jsonOutput:   opts.JSONOutput || opts.CSVOutput,
+		logical:      client.Logical(),
+		metadataOnly: true,
+		sys:          client.Sys(),
+		visit:        reporter.visit,
+		wg:           sync.WaitGroup{},
+	}
+
+	vc.crawl(kv2StartPaths(vc.startPaths(searchPath, opts.KvVersion)), fmt.Sprintf("Reporting secrets older "+
+		"than %s", opts.OlderThan))
+
+	switch {
+	case opts.CSVOutput:
+		reporter.writeCSV()
+	case opts.JSONOutput:
+		for _, age := range reporter.sorted() {
+			ageJSON, err := json.Marshal(age)
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "can't marshal JSON: %s\n", err)
+				continue
+			}
+			fmt.Println(string(ageJSON))
+		}
+	default:
+		reporter.showGroups(opts.OlderThan)
+	}
+}
+
+func (r *ageReporter) visit(dirEntry string, fullPath string, version int, metadata map[string]interface{}) {
+	updated, ok := secretUpdated(metadata)
+	if !ok {
+		_, _ = fmt.Fprintf(os.Stderr, "!!Warning!! %s has no creation time. Skipping.\n", fullPath)
+		return
+	}
+	mount, _, _ := strings.Cut(fullPath, "/")
+	customMetadata, _ := metadata["custom_metadata"].(map[string]interface{})
+	owner, _ := customMetadata[r.ownerKey].(string)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checked++
+	if r.groups[mount] == nil {
+		r.groups[mount] = map[string]*ageGroup{}
+	}
+	if r.groups[mount][owner] == nil {
+		r.groups[mount][owner] = &ageGroup{}
+	}
+	group := r.groups[mount][owner]
+	group.checked++
+
+	age := r.now.Sub(updated)
+	if age > r.olderThan {
+		group.stale = append(group.stale, secretAge{"age", fullPath, mount, owner, updated.Format(time.RFC3339),
+			int(age.Hours() / 24)})
+	}
+}
+
+// secretUpdated returns when the current version of a secret was created, or
+// when its metadata was updated if the version is unknown
+func secretUpdated(metadata map[string]interface{}) (time.Time, bool) {
+	versions, _ := metadata["versions"].(map[string]interface{})
+	current, _ := versions[fmt.Sprint(metadata["current_version"])].(map[string]interface{})
+	for _, created := range []interface{}{current["created_time"], metadata["updated_time"]} {
+		if s, ok := created.(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// sorted returns the secrets older than the threshold by mount, owner and age, oldest first
+func (r *ageReporter) sorted() []secretAge {
+	var ages []secretAge
+	for _, owners := range r.groups {
+		for _, group := range owners {
+			ages = append(ages, group.stale...)
+		}
+	}
+	sortAges(ages)
+	return ages
+}
+
+func sortAges(ages []secretAge) {
+	sort.Slice(ages, func(i, j int) bool {
+		if ages[i].Mount != ages[j].Mount {
+			return ages[i].Mount < ages[j].Mount
+		}
+		if ages[i].Owner != ages[j].Owner {
+			return ages[i].Owner < ages[j].Owner
+		}
+		if ages[i].AgeDays != ages[j].AgeDays {
+			return ages[i].AgeDays > ages[j].AgeDays
+		}
+		return ages[i].FullPath < ages[j].FullPath
+	})
+}
+
+func (r *ageReporter) writeCSV() {
+	w := csv.NewWriter(os.Stdout)
+	_ = w.Write([]string{"path", "mount", "owner", "updated", "age_days"})
+	for _, age := range r.sorted() {
+		_ = w.Write([]string{age.FullPath, age.Mount, age.Owner, age.Updated, strconv.Itoa(age.AgeDays)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "can't write CSV: %s\n", err)
+	}
+}
+
+func (r *ageReporter) showGroups(olderThan string) {
+	var mounts []string
+	for mount := range r.groups {
+		mounts = append(mounts, mount)
+	}
+	sort.Strings(mounts)
+
+	stale := 0
+	for _, mount := range mounts {
+		var owners []string
+		mountChecked, mountStale := 0, 0
+		for owner, group := range r.groups[mount] {
+			owners = append(owners, owner)
+			mountChecked += group.checked
+			mountStale += len(group.stale)
+		}
+		sort.Strings(owners)
+		fmt.Printf("Mount: %s, %d of %d secrets older than %s\n", mount, mountStale, mountChecked, olderThan)
+
+		for _, owner := range owners {
+			group := r.groups[mount][owner]
+			name := owner
+			if name == "" {
+				name = "(none)"
+			}
+			fmt.Printf("\tOwner: %s, %d of %d secrets older than %s\n", name, len(group.stale), group.checked,
+				olderThan)
+			sortAges(group.stale)
+			for _, age := range group.stale {
+				fmt.Printf("\t\t%s, updated %s, %d days ago\n", age.FullPath, age.Updated, age.AgeDays)
+			}
+		}
+		fmt.Println()
+		stale += mountStale
+	}
+	fmt.Printf("%d of %d checked secrets are older than %s\n", stale, r.checked, olderThan)
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testAgeReporter(t *testing.T) *ageReporter {
+	t.Helper()
+	reporter := &ageReporter{
+		now:       time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+		olderThan: 90 * 24 * time.Hour,
+		ownerKey:  "team",
+		groups:    map[string]map[string]*ageGroup{},
+	}
+	metadata := func(created string, updated string, team string) map[string]interface{} {
+		m := map[string]interface{}{
+			"current_version": json.Number("2"),
+			"updated_time":    updated,
+			"versions": map[string]interface{}{
+				"1": map[string]interface{}{"created_time": "2020-01-01T00:00:00Z"},
+				"2": map[string]interface{}{"created_time": created},
+			},
+		}
+		if team != "" {
+			m["custom_metadata"] = map[string]interface{}{"team": team}
+		}
+		return m
+	}
+
+	reporter.visit("db", "kv/prod/db", 2, metadata("2024-01-01T00:00:00.123Z", "2024-05-30T00:00:00Z", "payments"))
+	reporter.visit("api", "kv/prod/api", 2, metadata("2024-05-01T00:00:00Z", "2024-05-01T00:00:00Z", "payments"))
+	reporter.visit("old", "kv/prod/old", 2, metadata("2023-06-02T00:00:00Z", "2023-06-02T00:00:00Z", ""))
+	reporter.visit("ci", "ci/token", 2, metadata("", "2024-02-01T00:00:00Z", "platform"))
+	return reporter
+}
+
+func TestAgeReporter(t *testing.T) {
+	reporter := testAgeReporter(t)
+
+	expected := []secretAge{
+		{"age", "ci/token", "ci", "platform", "2024-02-01T00:00:00Z", 121},
+		{"age", "kv/prod/old", "kv", "", "2023-06-02T00:00:00Z", 365},
+		{"age", "kv/prod/db", "kv", "payments", "2024-01-01T00:00:00Z", 151},
+	}
+	if actual := reporter.sorted(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v, but got %v", expected, actual)
+	}
+	if reporter.checked != 4 || reporter.groups["kv"]["payments"].checked != 2 {
+		t.Errorf("Expected 4 checked secrets, 2 of them of payments, but got %d", reporter.checked)
+	}
+}
+
+func TestAgeReporterOutput(t *testing.T) {
+	reporter := testAgeReporter(t)
+
+	tests := []struct {
+		show     func()
+		expected string
+	}{
+		{reporter.writeCSV, `path,mount,owner,updated,age_days
+ci/token,ci,platform,2024-02-01T00:00:00Z,121
+kv/prod/old,kv,,2023-06-02T00:00:00Z,365
+kv/prod/db,kv,payments,2024-01-01T00:00:00Z,151`},
+		{func() { reporter.showGroups("90d") }, `Mount: ci, 1 of 1 secrets older than 90d
+	Owner: platform, 1 of 1 secrets older than 90d
+		ci/token, updated 2024-02-01T00:00:00Z, 121 days ago
+
+Mount: kv, 2 of 3 secrets older than 90d
+	Owner: (none), 1 of 1 secrets older than 90d
+		kv/prod/old, updated 2023-06-02T00:00:00Z, 365 days ago
+	Owner: payments, 1 of 2 secrets older than 90d
+		kv/prod/db, updated 2024-01-01T00:00:00Z, 151 days ago
+
+3 of 4 checked secrets are older than 90d`},
+	}
+
+	for _, tt := range tests {
+		stdout := os.Stdout
+		r, w, _ := os.Pipe()
+		os.Stdout = w
+		tt.show()
+		_ = w.Close()
+		os.Stdout = stdout
+
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(r)
+		if actual := strings.TrimSpace(buf.String()); actual != tt.expected {
+			t.Errorf("Expected\n%s\nbut got\n%s", tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// decodedField is a virtual key/value pair decoded from a structured value,
+// like the subject of a PEM certificate. It is searched under the key of the
+// value it was decoded from, followed by # and the field name, e.g.
+// tls.crt#x509.subject.
+type decodedField struct {
+	name  string
+	value string
+}
+
+// valueDecoder returns the fields of value, or nil if it isn't in the format the decoder understands
+type valueDecoder func(value string) []decodedField
+
+// valueDecoders are the choices of the --decode flag
+var valueDecoders = map[string]valueDecoder{
+	"jwt":  decodeJWT,
+	"ssh":  decodeSSH,
+	"x509": decodeX509,
+}
+
+// decoderNames returns the sorted names of the available decoders
+func decoderNames() []string {
+	var names []string
+	for name := range valueDecoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupDecoders returns the decoders with the given names
+func lookupDecoders(names []string) ([]valueDecoder, error) {
+	var decoders []valueDecoder
+	for _, name := range names {
+		decoder, ok := valueDecoders[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("%s is not a valid decoder. Choices are %v", name, decoderNames())
+		}
+		decoders = append(decoders, decoder)
+	}
+	return decoders, nil
+}
+
+// decodeValue returns the fields every decoder finds in value
+func decodeValue(decoders []valueDecoder, value string) []decodedField {
+	var fields []decodedField
+	for _, decoder := range decoders {
+		fields = append(fields, decoder(value)...)
+	}
+	return fields
+}
+
+// parseDuration is like time.ParseDuration, but also accepts days and weeks, e.g. 30d or 2w. Negative
+// durations are rejected.
+func parseDuration(s string) (time.Duration, error) {
+	d, err := parseSignedDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid duration %q, it can't be negative", s)
+	}
+	return d, nil
+}
+
+func parseSignedDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(count * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		valid    bool
+	}{
+		{"30d", 30 * 24 * time.Hour, true},
+		{"2w", 14 * 24 * time.Hour, true},
+		{"1.5d", 36 * time.Hour, true},
+		{"12h", 12 * time.Hour, true},
+		{"90m", 90 * time.Minute, true},
+		{"d", 0, false},
+		{"soon", 0, false},
+		{"0s", 0, true},
+		{"-5d", 0, false},
+		{"-1h", 0, false},
+	}
+
+	for _, tt := range tests {
+		actual, err := parseDuration(tt.input)
+		if tt.valid && (err != nil || actual != tt.expected) {
+			t.Errorf("Expected %v for '%s', but got %v (%v)", tt.expected, tt.input, actual, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("Expected '%s' to be rejected, but got %v", tt.input, actual)
+		}
+	}
+}
+
+func TestLookupDecoders(t *testing.T) {
+	if decoders, err := lookupDecoders([]string{"X509"}); err != nil || len(decoders) != 1 {
+		t.Errorf("Expected the x509 decoder, but got %d decoders (%v)", len(decoders), err)
+	}
+	if _, err := lookupDecoders([]string{"x509", "asn1"}); err == nil {
+		t.Error("Expected unknown decoder to be rejected")
+	}
+}
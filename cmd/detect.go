@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+)
+
+// detectionRule finds a kind of structured credential in values
+type detectionRule struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Pattern     string `json:"pattern"`
+	// MinEntropy, if set, is the minimum Shannon entropy in bits per character of the matched text, or of its
+	// first capture group if the pattern has one
+	MinEntropy float64 `json:"min_entropy,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// defaultDetectionRules are the built-in credential detectors
+var defaultDetectionRules = []detectionRule{
+	{ID: "aws-access-key-id", Description: "AWS access key ID", Pattern: `\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`},
+	{ID: "aws-secret-access-key", Description: "AWS secret access key", Pattern: `(?:^|[^A-Za-z0-9/+=])([A-Za-z0-9/+=]{40})(?:$|[^A-Za-z0-9/+=])`, MinEntropy: 4.5},
+	{ID: "github-token", Description: "GitHub token", Pattern: `\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}\b|\bgithub_pat_[A-Za-z0-9_]{82}\b`},
+	{ID: "gitlab-token", Description: "GitLab personal access token", Pattern: `\bglpat-[A-Za-z0-9_-]{20}\b`},
+	{ID: "google-api-key", Description: "Google API key", Pattern: `\bAIza[0-9A-Za-z_-]{35}\b`},
+	{ID: "jwt", Description: "JSON Web Token", Pattern: `\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]*`},
+	{ID: "private-key", Description: "PEM encoded private key", Pattern: `-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`},
+	{ID: "slack-token", Description: "Slack token", Pattern: `\bxox[baprs]-[A-Za-z0-9-]{10,}\b`},
+	{ID: "slack-webhook", Description: "Slack incoming webhook", Pattern: `https://hooks\.slack\.com/services/T[A-Z0-9]+/B[A-Z0-9]+/[A-Za-z0-9]+`},
+	{ID: "stripe-key", Description: "Stripe live key", Pattern: `\b(?:sk|rk)_live_[0-9A-Za-z]{24,}\b`},
+	{ID: "vault-token", Description: "Vault service or batch token", Pattern: `\bhv[sb]\.[A-Za-z0-9_-]{24,}`},
+}
+
+// loadDetectionRules returns the built-in rules extended by the JSON array of
+// rules in path, if given. A rule with the ID of a built-in rule replaces it.
+func loadDetectionRules(path string) ([]detectionRule, error) {
+	rules := append([]detectionRule(nil), defaultDetectionRules...)
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read detection rules: %w", err)
+		}
+		var custom []detectionRule
+		if err := json.Unmarshal(data, &custom); err != nil {
+			return nil, fmt.Errorf("failed to parse detection rules %s: %w", path, err)
+		}
+
+	custom:
+		for _, rule := range custom {
+			if rule.ID == "" || rule.Pattern == "" {
+				return nil, fmt.Errorf("detection rule %q in %s needs an id and a pattern", rule.ID, path)
+			}
+			for i := range rules {
+				if rules[i].ID == rule.ID {
+					rules[i] = rule
+					continue custom
+				}
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	for i := range rules {
+		re, err := regexp.Compile(rules[i].Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of detection rule %s: %w", rules[i].ID, err)
+		}
+		rules[i].re = re
+	}
+
+	return rules, nil
+}
+
+// detectMatcher reports the ID of every detection rule firing on a value
+type detectMatcher struct {
+	rules []detectionRule
+}
+
+func newDetectMatcher(rules []detectionRule) Matcher {
+	return detectMatcher{rules}
+}
+
+func (m detectMatcher) Match(s string) []Hit {
+	var hits []Hit
+	for _, rule := range m.rules {
+		if rule.fires(s) {
+			hits = append(hits, Hit{Rule: rule.ID})
+		}
+	}
+	return hits
+}
+
+func (r detectionRule) fires(s string) bool {
+	for _, match := range r.re.FindAllStringSubmatch(s, -1) {
+		if r.MinEntropy == 0 {
+			return true
+		}
+		text := match[0]
+		if len(match) > 1 {
+			text = match[1]
+		}
+		if shannonEntropy(text) >= r.MinEntropy {
+			return true
+		}
+	}
+	return false
+}
+
+// shannonEntropy returns the Shannon entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(duplicatesCmd)
+
+	duplicatesCmd.Flags().IntVar(&duplicatesMinLength, "min-length", 8, "Ignore values shorter than this many "+
+		"characters, like 'true' or '5432'")
+	duplicatesCmd.Flags().StringSliceVar(&duplicatesKeys, "keys", nil, "Only compare values of keys matching "+
+		"these case-insensitive globs, e.g. '*password*,*token*'")
+	duplicatesCmd.Flags().StringSliceVar(&duplicatesExcludeKeys, "exclude-keys", nil, "Ignore values of keys "+
+		"matching these case-insensitive globs, e.g. '*port,*_enabled'")
+}
+
+var (
+	duplicatesExcludeKeys []string
+	duplicatesKeys        []string
+	duplicatesMinLength   int
+)
+
+var duplicatesCmd = &cobra.Command{
+	Use:   "duplicates [flags] [search-path]",
+	Short: "Find values shared by several secrets",
+	Long: `Find values reused across paths and mounts, e.g. the same database password in staging and prod
+
+Every value is fingerprinted with a keyed hash whose key only lives in memory
+for the duration of the run, and groups of paths and keys sharing the same value
+are reported. Values are never shown. If no search-path is given, all available
+KV stores are crawled`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		for _, globs := range [][]string{duplicatesKeys, duplicatesExcludeKeys} {
+			if _, err := compileGlobs(globs); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var searchPath string
+		if len(args) == 1 {
+			searchPath = args[0]
+		}
+		VaultDuplicates(searchPath, DuplicatesOptions{
+			CrawlingDelay: crawlingDelay,
+			ExcludeKeys:   duplicatesExcludeKeys,
+			JSONOutput:    jsonOutput,
+			Keys:          duplicatesKeys,
+			KvVersion:     kvVersion,
+			MinLength:     duplicatesMinLength,
+			Timeout:       timeout,
+		})
+	},
+	Example: "vault-kv-search duplicates --exclude-keys '*port' secret/",
+}
+
+// DuplicatesOptions holds the settings of a duplicate value search
+type DuplicatesOptions struct {
+	CrawlingDelay int
+	// ExcludeKeys are globs of key names whose values are ignored
+	ExcludeKeys []string
+	JSONOutput  bool
+	// Keys, if set, are globs of the only key names whose values are compared
+	Keys      []string
+	KvVersion int
+	// MinLength ignores values shorter than this many characters
+	MinLength int
+	Timeout   int
+}
+
+type duplicateLocation struct {
+	FullPath string `json:"path"`
+	Key      string `json:"key"`
+}
+
+type duplicateGroup struct {
+	Search  string              `json:"search"`
+	Count   int                 `json:"count"`
+	Secrets []duplicateLocation `json:"secrets"`
+}
+
+// duplicateFinder groups the locations of values by their fingerprint
+type duplicateFinder struct {
+	excludeKeys []*regexp.Regexp
+	keys        []*regexp.Regexp
+	minLength   int
+	secret      []byte
+
+	mu        sync.Mutex
+	locations map[string][]duplicateLocation
+}
+
+// VaultDuplicates reports values shared by several secrets below searchPath, or all KV stores if it is empty
+func VaultDuplicates(searchPath string, opts DuplicatesOptions) {
+	client := newVaultClient(opts.Timeout)
+
+	finder := &duplicateFinder{minLength: opts.MinLength, locations: map[string][]duplicateLocation{}}
+	var err error
+	if finder.keys, err = compileGlobs(opts.Keys); err == nil {
+		finder.excludeKeys, err = compileGlobs(opts.ExcludeKeys)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(exitStatusError)
+	}
+
+	// The fingerprint key is random and never leaves memory, so fingerprints can't be brute forced offline
+	finder.secret = make([]byte, 32)
+	if _, err := rand.Read(finder.secret); err != nil {
+		fmt.Println(fmt.Errorf("failed to generate fingerprint key: %w", err))
+		os.Exit(exitStatusError)
+	}
+
+	vc := vaultClient{
+		crawlingDelay: opts.CrawlingDelay,
+		jsonOutput:    opts.JSONOutput,
+		logical:       client.Logical(),
+		sys:           client.Sys(),
+		visit:         finder.visit,
+		wg:            sync.WaitGroup{},
+	}
+
+	vc.crawl(vc.startPaths(searchPath, opts.KvVersion), "Searching for duplicate values")
+
+	for _, group := range finder.groups() {
+		vc.showDuplicates(group)
+	}
+}
+
+func (f *duplicateFinder) visit(dirEntry string, fullPath string, version int, data map[string]interface{}) {
+	walkValues(version, data, func(v secretValue) {
+		if utf8.RuneCountInString(v.value) < f.minLength {
+			return
+		}
+		if len(f.keys) > 0 && !matchAnyGlob(f.keys, v.key) {
+			return
+		}
+		if matchAnyGlob(f.excludeKeys, v.key) {
+			return
+		}
+
+		mac := hmac.New(sha256.New, f.secret)
+		mac.Write([]byte(v.value))
+		fingerprint := string(mac.Sum(nil))
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.locations[fingerprint] = append(f.locations[fingerprint], duplicateLocation{fullPath, v.path})
+	})
+}
+
+// groups returns the values found in more than one place, largest groups first
+func (f *duplicateFinder) groups() []duplicateGroup {
+	var groups []duplicateGroup
+	for _, locations := range f.locations {
+		if len(locations) < 2 {
+			continue
+		}
+		sort.Slice(locations, func(i, j int) bool {
+			if locations[i].FullPath != locations[j].FullPath {
+				return locations[i].FullPath < locations[j].FullPath
+			}
+			return locations[i].Key < locations[j].Key
+		})
+		groups = append(groups, duplicateGroup{"duplicate", len(locations), locations})
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Secrets[0].FullPath < groups[j].Secrets[0].FullPath
+	})
+	return groups
+}
+
+func (vc *vaultClient) showDuplicates(group duplicateGroup) {
+	if vc.jsonOutput {
+		groupJSON, err := json.Marshal(group)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "can't marshal JSON: %s\n", err)
+			return
+		}
+		fmt.Println(string(groupJSON))
+	} else {
+		fmt.Printf("Duplicate value in %d places:\n", group.Count)
+		for _, location := range group.Secrets {
+			fmt.Printf("\tSecret: %s, Key: %s\n", location.FullPath, location.Key)
+		}
+		fmt.Println()
+	}
+}
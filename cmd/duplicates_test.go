@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDuplicateFinder(t *testing.T) {
+	excludeKeys, err := compileGlobs([]string{"*port"})
+	if err != nil {
+		t.Fatalf("Failed to compile globs: %v", err)
+	}
+
+	finder := &duplicateFinder{
+		excludeKeys: excludeKeys,
+		minLength:   6,
+		secret:      []byte("test-key"),
+		locations:   map[string][]duplicateLocation{},
+	}
+
+	finder.visit("db", "kv/prod/db", 1, map[string]interface{}{
+		"password": "s3cr3t-pass",
+		"port":     "543210",
+		"enabled":  true,
+	})
+	finder.visit("db", "kv/staging/db", 1, map[string]interface{}{
+		"password": "s3cr3t-pass",
+		"port":     "543210",
+		"enabled":  true,
+	})
+	finder.visit("cache", "kv2/dev/cache", 2, map[string]interface{}{
+		"data": map[string]interface{}{
+			"auth": map[string]interface{}{"token": "s3cr3t-pass"},
+		},
+		"metadata": map[string]interface{}{"created_time": "2024-01-01"},
+	})
+	finder.visit("other", "kv/prod/other", 1, map[string]interface{}{
+		"password": "unique-value",
+	})
+
+	expected := []duplicateGroup{{
+		Search: "duplicate",
+		Count:  3,
+		Secrets: []duplicateLocation{
+			{"kv/prod/db", "password"},
+			{"kv/staging/db", "password"},
+			{"kv2/dev/cache", "auth.token"},
+		},
+	}}
+
+	if actual := finder.groups(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected groups %v, but got %v", expected, actual)
+	}
+}
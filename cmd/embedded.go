@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseEmbedded detects JSON, INI, dotenv and YAML documents stored as a
+// single value and returns their key/value pairs, with nested keys joined by
+// dots and list items indexed, like database.password or servers[0].host.
+// It returns nil if value isn't a document of any of these formats.
+func parseEmbedded(value string) []decodedField {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return nil
+	}
+
+	var fields []decodedField
+	collect := func(path string, value string) {
+		fields = append(fields, decodedField{path, value})
+	}
+
+	// JSON objects and arrays
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		decoder := json.NewDecoder(strings.NewReader(trimmed))
+		decoder.UseNumber()
+		var document interface{}
+		if err := decoder.Decode(&document); err == nil && !decoder.More() {
+			flattenEmbedded("", document, collect)
+			return fields
+		}
+	}
+
+	// The line based formats need several lines, so that values like "user=admin" aren't mistaken for documents
+	if !strings.Contains(trimmed, "\n") {
+		return nil
+	}
+	if pairs, ok := parseINI(trimmed); ok {
+		return pairs
+	}
+	if pairs, ok := parseDotenv(trimmed); ok {
+		return pairs
+	}
+
+	var document interface{}
+	if err := yaml.Unmarshal([]byte(trimmed), &document); err == nil {
+		switch document.(type) {
+		case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+			flattenEmbedded("", document, collect)
+			return fields
+		}
+	}
+
+	return nil
+}
+
+// flattenEmbedded calls fn with the path and string value of every scalar in a decoded JSON or YAML document
+func flattenEmbedded(path string, document interface{}, fn func(path string, value string)) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch v := document.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			flattenEmbedded(join(key), v[key], fn)
+		}
+	case map[interface{}]interface{}:
+		converted := map[string]interface{}{}
+		for key, value := range v {
+			converted[fmt.Sprint(key)] = value
+		}
+		flattenEmbedded(path, converted, fn)
+	case []interface{}:
+		for i, item := range v {
+			flattenEmbedded(fmt.Sprintf("%s[%d]", path, i), item, fn)
+		}
+	case nil:
+	default:
+		fn(path, fmt.Sprint(v))
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var (
+	iniSection = regexp.MustCompile(`^\[([^\[\]]+)\]$`)
+	envKey     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+)
+
+// parseINI parses an INI document with at least one [section]. Keys are
+// prefixed with their section, like database.password.
+func parseINI(document string) ([]decodedField, bool) {
+	var fields []decodedField
+	section := ""
+	sections := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(document))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := iniSection.FindStringSubmatch(line); m != nil {
+			section = strings.TrimSpace(m[1])
+			sections++
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, false
+		}
+		if section != "" {
+			key = section + "." + key
+		}
+		fields = append(fields, decodedField{key, unquoteEmbedded(strings.TrimSpace(value))})
+	}
+
+	return fields, sections > 0 && len(fields) > 0
+}
+
+// parseDotenv parses a dotenv document, where every line is KEY=VALUE, optionally preceded by export
+func parseDotenv(document string) ([]decodedField, bool) {
+	var fields []decodedField
+
+	scanner := bufio.NewScanner(strings.NewReader(document))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || !envKey.MatchString(key) {
+			return nil, false
+		}
+		fields = append(fields, decodedField{key, unquoteEmbedded(strings.TrimSpace(value))})
+	}
+
+	return fields, len(fields) > 0
+}
+
+// unquoteEmbedded removes the quotes around an INI or dotenv value
+func unquoteEmbedded(value string) string {
+	if len(value) >= 2 {
+		switch {
+		case value[0] == '"' && value[len(value)-1] == '"':
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				return unquoted
+			}
+			return value[1 : len(value)-1]
+		case value[0] == '\'' && value[len(value)-1] == '\'':
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
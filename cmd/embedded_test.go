@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEmbedded(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []decodedField
+	}{
+		{"json", `{"database": {"user": "app", "password": "s3cret", "port": 5432}, "hosts": ["a", "b"], "debug": false}`,
+			[]decodedField{
+				{"database.password", "s3cret"},
+				{"database.port", "5432"},
+				{"database.user", "app"},
+				{"debug", "false"},
+				{"hosts[0]", "a"},
+				{"hosts[1]", "b"},
+			}},
+		{"json array", `[{"name": "ci", "secret": "abc"}]`,
+			[]decodedField{{"[0].name", "ci"}, {"[0].secret", "abc"}}},
+		{"yaml", "database:\n  password: s3cret\nclients:\n  - id: web\n    secret: xyz\n",
+			[]decodedField{
+				{"clients[0].id", "web"},
+				{"clients[0].secret", "xyz"},
+				{"database.password", "s3cret"},
+			}},
+		{"ini", "; comment\nname = app\n[database]\npassword = \"s3 cret\"\nhost=db\n",
+			[]decodedField{{"name", "app"}, {"database.password", "s3 cret"}, {"database.host", "db"}}},
+		{"dotenv", "# env\nexport DB_PASSWORD='s3cret'\nDB_HOST=db\n",
+			[]decodedField{{"DB_PASSWORD", "s3cret"}, {"DB_HOST", "db"}}},
+		{"single line", "user=admin", nil},
+		{"plain text", "hello world\nsecond line", nil},
+		{"plain string", "s3cret", nil},
+		{"invalid json", `{"unterminated": `, nil},
+		{"pem", "-----BEGIN CERTIFICATE-----\nMIIB\nabc=\n-----END CERTIFICATE-----\n", nil},
+	}
+
+	for _, tt := range tests {
+		if actual := parseEmbedded(tt.value); !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Expected %s fields %v, but got %v", tt.name, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+)
+
+// FuzzyOptions configure how close a value must be to the search string to match
+type FuzzyOptions struct {
+	// MaxDistance is the maximum edit distance of a match
+	MaxDistance int
+	// MinSimilarity, if set, replaces MaxDistance with a minimum similarity ratio between 0 and 1
+	MinSimilarity float64
+	// MaxLength skips values longer than this many characters, as edit distance is quadratic
+	MaxLength int
+}
+
+var defaultFuzzyOptions = FuzzyOptions{MaxDistance: 2, MaxLength: 256}
+
+// fuzzyMatcher matches whole values within an edit distance of the pattern,
+// e.g. rotated credentials that only differ by an appended digit
+type fuzzyMatcher struct {
+	pattern   []rune
+	normalize func(string) string
+	opts      FuzzyOptions
+}
+
+func newFuzzyMatcher(pattern string, ignoreCase bool, opts FuzzyOptions) (Matcher, error) {
+	if opts.MaxDistance < 0 {
+		return nil, fmt.Errorf("max distance must not be negative, got %d", opts.MaxDistance)
+	}
+	if opts.MinSimilarity < 0 || opts.MinSimilarity > 1 {
+		return nil, fmt.Errorf("min similarity must be between 0 and 1, got %v", opts.MinSimilarity)
+	}
+	if opts.MaxLength <= 0 {
+		return nil, fmt.Errorf("max length must be positive, got %d", opts.MaxLength)
+	}
+
+	normalize := func(s string) string { return s }
+	if ignoreCase {
+		normalize = foldString
+	}
+	return fuzzyMatcher{[]rune(normalize(pattern)), normalize, opts}, nil
+}
+
+func (m fuzzyMatcher) Match(s string) []Hit {
+	value := []rune(m.normalize(s))
+	if len(value) > m.opts.MaxLength {
+		return nil
+	}
+
+	limit := m.opts.MaxDistance
+	if m.opts.MinSimilarity > 0 {
+		// similarity = 1 - distance / longest, so this is the largest distance still similar enough
+		// The epsilon keeps e.g. (1 - 0.8) * 10 from rounding down to 1
+		limit = int((1-m.opts.MinSimilarity)*float64(max(len(value), len(m.pattern))) + 1e-9)
+	}
+
+	distance, ok := levenshtein(m.pattern, value, limit)
+	if !ok {
+		return nil
+	}
+	return []Hit{{Distance: &distance}}
+}
+
+// levenshtein returns the edit distance between a and b, giving up once it
+// is certain to exceed limit.
+func levenshtein(a []rune, b []rune, limit int) (int, bool) {
+	if abs(len(a)-len(b)) > limit {
+		return 0, false
+	}
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		rowMin := current[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			rowMin = min(rowMin, current[j])
+		}
+		if rowMin > limit {
+			return 0, false
+		}
+		previous, current = current, previous
+	}
+
+	if distance := previous[len(b)]; distance <= limit {
+		return distance, true
+	}
+	return 0, false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
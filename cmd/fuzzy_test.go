@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"hunter2", "hunter3", 1},
+		{"hunter2", "hunter22", 1},
+		{"naïve", "naive", 1},
+	}
+
+	for _, tt := range tests {
+		distance, ok := levenshtein([]rune(tt.a), []rune(tt.b), 10)
+		if !ok || distance != tt.expected {
+			t.Errorf("Expected distance %d between '%s' and '%s', but got %d (%v)", tt.expected, tt.a, tt.b, distance, ok)
+		}
+	}
+
+	if _, ok := levenshtein([]rune("kitten"), []rune("sitting"), 2); ok {
+		t.Error("Expected distance over the limit to be rejected")
+	}
+}
+
+func TestFuzzyMatcher(t *testing.T) {
+	matcher, err := newFuzzyMatcher("Password1", true, FuzzyOptions{MaxDistance: 1, MaxLength: 20})
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+
+	hits := matcher.Match("PASSWORD12")
+	if len(hits) != 1 || hits[0].Distance == nil || *hits[0].Distance != 1 {
+		t.Errorf("Expected a hit with distance 1, but got %v", hits)
+	}
+	if matcher.Match("Password123") != nil {
+		t.Error("Expected distance 2 not to match")
+	}
+	if matcher.Match(strings.Repeat("a", 21)) != nil {
+		t.Error("Expected values over the max length to be skipped")
+	}
+
+	matcher, err = newFuzzyMatcher("abcdefghij", false, FuzzyOptions{MinSimilarity: 0.8, MaxLength: 20})
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+	if matcher.Match("abcdefghXY") == nil {
+		t.Error("Expected similarity 0.8 to match")
+	}
+	if matcher.Match("abcdefgXYZ") != nil {
+		t.Error("Expected similarity 0.7 not to match")
+	}
+
+	if _, err := newFuzzyMatcher("foo", false, FuzzyOptions{MinSimilarity: 2, MaxLength: 20}); err == nil {
+		t.Error("Expected invalid similarity to be rejected")
+	}
+}
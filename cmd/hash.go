@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// hashAlgorithms are the choices of the --hash flag
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// hashMatcher matches values whose hash equals a known digest, so a leaked
+// credential can be found without handling its plaintext
+type hashMatcher struct {
+	newHash func() hash.Hash
+	digest  []byte
+}
+
+// newHashMatcher parses a hash spec of the form <algorithm>:<hex digest>, e.g.
+// sha256:9f86d0... HMAC digests use hmac-<algorithm> and need hmacKey.
+func newHashMatcher(spec string, hmacKey []byte) (Matcher, error) {
+	algorithm, digestHex, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid hash %q, expected <algorithm>:<hex digest>", spec)
+	}
+
+	algorithm = strings.ToLower(algorithm)
+	name, isHMAC := strings.CutPrefix(algorithm, "hmac-")
+	newHash, ok := hashAlgorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is not a valid hash algorithm. Choices are md5, sha1, sha256, sha512 and their hmac- variants", algorithm)
+	}
+	if isHMAC {
+		if len(hmacKey) == 0 {
+			return nil, errors.New("an HMAC key is needed for " + algorithm)
+		}
+		inner := newHash
+		newHash = func() hash.Hash { return hmac.New(inner, hmacKey) }
+	}
+
+	digest, err := hex.DecodeString(strings.TrimSpace(digestHex))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s digest: %w", algorithm, err)
+	}
+	if size := newHash().Size(); len(digest) != size {
+		return nil, fmt.Errorf("invalid %s digest, expected %d bytes but got %d", algorithm, size, len(digest))
+	}
+
+	return hashMatcher{newHash, digest}, nil
+}
+
+func (m hashMatcher) Match(s string) []Hit {
+	h := m.newHash()
+	h.Write([]byte(s))
+	return hitIf(hmac.Equal(h.Sum(nil), m.digest))
+}
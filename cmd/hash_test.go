@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestHashMatcher(t *testing.T) {
+	tests := []struct {
+		spec    string
+		hmacKey string
+	}{
+		{"md5:5f4dcc3b5aa765d61d8327deb882cf99", ""},
+		{"sha1:5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", ""},
+		{"SHA256:5E884898DA28047151D0E56F8DC6292773603D0D6AABBDD62A11EF721D1542D8", ""},
+		{"hmac-sha256:4d42fb9ffc8d7d0a245429438b4bc73db1007a167026a0a0c6a74fa58e8e86ca", "key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			matcher, err := newHashMatcher(tt.spec, []byte(tt.hmacKey))
+			if err != nil {
+				t.Fatalf("Failed to create matcher: %v", err)
+			}
+			if matcher.Match("password") == nil {
+				t.Error("Expected the digest of 'password' to match")
+			}
+			if matcher.Match("password ") != nil {
+				t.Error("Expected a different value not to match")
+			}
+		})
+	}
+}
+
+func TestHashMatcherErrors(t *testing.T) {
+	for _, spec := range []string{
+		"5f4dcc3b5aa765d61d8327deb882cf99",
+		"crc32:deadbeef",
+		"sha256:zz",
+		"sha256:5f4dcc3b5aa765d61d8327deb882cf99",
+		"hmac-sha256:5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8",
+	} {
+		if _, err := newHashMatcher(spec, nil); err == nil {
+			t.Errorf("Expected hash '%s' to be rejected", spec)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+func init() {
+	RootCmd.AddCommand(identityCmd)
+
+	identityCmd.Flags().StringSliceVar(&identitySearchObjects, "search", []string{"name", "key", "value"}, "Which "+
+		"identity objects to search against. Choices are any and all of the following 'name,key,value', where key "+
+		"and value refer to metadata. Can be specified multiple times or once using format CSV")
+}
+
+var identitySearchObjects []string
+
+var identityCmd = &cobra.Command{
+	Use:   "identity [flags] substring",
+	Short: "Search identity entities, aliases and groups",
+	Long: `Search identity entities, entity aliases, groups and group aliases for substring
+
+Names, metadata keys and metadata values are matched, reporting the object type
+and ID of every match`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		for _, s := range identitySearchObjects {
+			if s != "name" && s != "key" && s != "value" {
+				return errors.New(fmt.Sprintf("%s is not a valid flag choice. Choices are [name key value]", s))
+			}
+		}
+		_, err := searchMatcher(args[0])
+		return err
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		matcher, _ := searchMatcher(args[0])
+		if VaultIdentitySearch(args[0], identitySearchObjects, matcher, jsonOutput, timeout) == 0 {
+			os.Exit(exitStatusNoMatch)
+		}
+	},
+	Example: "vault-kv-search identity --search value user@example.com",
+}
+
+type identityMatched struct {
+	Search string `json:"search"`
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Key    string `json:"key,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
+// VaultIdentitySearch searches identity entities and groups, including their aliases, for searchString. It
+// returns the number of matches.
+func VaultIdentitySearch(searchString string, searchObjects []string, matcher Matcher, jsonOutput bool,
+	timeoutSeconds int) int {
+	client := newVaultClient(timeoutSeconds)
+
+	vc := vaultClient{
+		jsonOutput:    jsonOutput,
+		logical:       client.Logical(),
+		matcher:       matcher,
+		searchObjects: searchObjects,
+		searchString:  searchString,
+	}
+
+	if !vc.jsonOutput {
+		fmt.Printf("Searching for substring '%s' against: %v\n", searchString, searchObjects)
+	}
+
+	for _, objectType := range []string{"entity", "group"} {
+		if err := vc.readIdentities(objectType); err != nil {
+			fmt.Println(err)
+			os.Exit(exitStatusError)
+		}
+	}
+	return vc.results
+}
+
+// readIdentities lists and matches every identity object of objectType, which
+// is either entity or group.
+func (vc *vaultClient) readIdentities(objectType string) error {
+	idList, err := vc.logical.List(fmt.Sprintf("identity/%s/id", objectType))
+	if err != nil {
+		return fmt.Errorf("failed to list %s identities: %w", objectType, err)
+	}
+	if idList == nil {
+		return nil
+	}
+
+	for _, x := range idList.Data["keys"].([]interface{}) {
+		id := x.(string)
+		secret, err := vc.logical.Read(fmt.Sprintf("identity/%s/id/%s", objectType, id))
+		if err != nil {
+			return fmt.Errorf("failed to read %s %s: %w", objectType, id, err)
+		}
+		if secret == nil {
+			continue
+		}
+
+		vc.identityMatch(objectType, secret.Data)
+
+		switch objectType {
+		case "entity":
+			aliases, _ := secret.Data["aliases"].([]interface{})
+			for _, alias := range aliases {
+				if data, ok := alias.(map[string]interface{}); ok {
+					vc.identityMatch("entity-alias", data)
+				}
+			}
+		case "group":
+			// Only external groups have an alias, internal groups return an empty map
+			if data, ok := secret.Data["alias"].(map[string]interface{}); ok && len(data) > 0 {
+				vc.identityMatch("group-alias", data)
+			}
+		}
+	}
+	return nil
+}
+
+func (vc *vaultClient) identityMatch(objectType string, data map[string]interface{}) {
+	id, _ := data["id"].(string)
+	name, _ := data["name"].(string)
+	metadata, _ := data["metadata"].(map[string]interface{})
+
+	// Sort metadata keys so matches are reported in a stable order
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, searchObject := range vc.searchObjects {
+		switch searchObject {
+		case "name":
+			if vc.matcher.Match(name) != nil {
+				vc.results++
+				vc.showIdentityMatch(identityMatched{searchObject, objectType, id, name, "", ""})
+			}
+		case "key", "value":
+			for _, key := range keys {
+				value := fmt.Sprint(metadata[key])
+				term := map[string]string{"key": key, "value": value}[searchObject]
+				if vc.matcher.Match(term) != nil {
+					vc.results++
+					vc.showIdentityMatch(identityMatched{searchObject, objectType, id, name, key, value})
+				}
+			}
+		}
+	}
+}
+
+func (vc *vaultClient) showIdentityMatch(identity identityMatched) {
+	if vc.jsonOutput {
+		identityJSON, err := json.Marshal(identity)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "can't marshal JSON: %s\n", err)
+			return
+		}
+		fmt.Println(string(identityJSON))
+	} else {
+		title := cases.Title(language.English)
+		fmt.Printf("%s match:\n\tType: %s\n\tID: %s\n\tName: %s\n", title.String(identity.Search), identity.Type, identity.ID, identity.Name)
+		if identity.Search != "name" {
+			fmt.Printf("\tKey: %s\n\tValue: %s\n", identity.Key, identity.Value)
+		}
+		fmt.Println()
+	}
+}
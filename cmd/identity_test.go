@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIdentitySearch(t *testing.T) {
+	client, closer := testVaultServerWithTestcontainers(t)
+	defer closer()
+
+	logical := client.Logical()
+
+	entity, err := logical.Write("identity/entity", map[string]interface{}{
+		"name":     "alice",
+		"metadata": map[string]interface{}{"email": "alice@example.com", "team": "payments"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to write entity: %v", err)
+	}
+	entityID := entity.Data["id"].(string)
+
+	group, err := logical.Write("identity/group", map[string]interface{}{
+		"name":     "payments-admins",
+		"metadata": map[string]interface{}{"owner": "bob@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to write group: %v", err)
+	}
+	groupID := group.Data["id"].(string)
+
+	// Redirect stdout to a buffer
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	// Configure the vault client environment variables
+	if err := os.Setenv("VAULT_TOKEN", client.Token()); err != nil {
+		t.Fatalf("failed to set VAULT_TOKEN: %v", err)
+	}
+	if err := os.Setenv("VAULT_ADDR", client.Address()); err != nil {
+		t.Fatalf("failed to set VAULT_ADDR: %v", err)
+	}
+
+	matcher, err := newMatcher("substring", "example.com", false, defaultFuzzyOptions)
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+
+	matches := VaultIdentitySearch("example.com", []string{"name", "value"}, matcher, true, 30)
+
+	// Read from the buffer to get the stdout output
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+
+	actualOutput := strings.TrimSpace(buf.String())
+	expectedOutput := fmt.Sprintf("%v\n%v",
+		fmt.Sprintf(`{"search":"value","type":"entity","id":"%s","name":"alice","key":"email","value":"alice@example.com"}`, entityID),
+		fmt.Sprintf(`{"search":"value","type":"group","id":"%s","name":"payments-admins","key":"owner","value":"bob@example.com"}`, groupID),
+	)
+
+	if actualOutput != expectedOutput {
+		t.Errorf("Expected output '%s', but got '%s'", expectedOutput, actualOutput)
+	}
+	if matches != 2 {
+		t.Errorf("Expected 2 matches, but got %d", matches)
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// jwtPattern finds JWTs in values, e.g. after "Bearer "
+var jwtPattern = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]*={0,2}\.eyJ[A-Za-z0-9_-]*={0,2}\.[A-Za-z0-9_-]*={0,2}`)
+
+// jwtToken holds the header and claims of a JWT. Its signature isn't verified.
+type jwtToken struct {
+	header map[string]interface{}
+	claims map[string]interface{}
+}
+
+// parseJWTs returns the JWTs found in value, skipping those whose header or claims aren't JSON objects
+func parseJWTs(value string) []jwtToken {
+	if !strings.Contains(value, "eyJ") {
+		return nil
+	}
+
+	var tokens []jwtToken
+	for _, match := range jwtPattern.FindAllString(value, -1) {
+		parts := strings.Split(match, ".")
+		header, err := decodeJWTPart(parts[0])
+		if err != nil {
+			continue
+		}
+		claims, err := decodeJWTPart(parts[1])
+		if err != nil {
+			continue
+		}
+		tokens = append(tokens, jwtToken{header, claims})
+	}
+	return tokens
+}
+
+func decodeJWTPart(part string) (map[string]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(part, "="))
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// expiry returns the exp claim, if the token has one
+func (t jwtToken) expiry() (time.Time, bool) {
+	exp, ok := t.claims["exp"].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := exp.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0).UTC(), true
+}
+
+// label names the token by its subject or issuer
+func (t jwtToken) label() string {
+	for _, claim := range []string{"sub", "iss"} {
+		if s, ok := t.claims[claim].(string); ok && s != "" {
+			return fmt.Sprintf("JWT %s=%s", claim, s)
+		}
+	}
+	return "JWT"
+}
+
+// decodeJWT exposes the alg, typ and kid header fields and the iss, sub, aud
+// and exp claims of JWTs. Tokens after the first one are numbered, like
+// jwt[1].sub.
+func decodeJWT(value string) []decodedField {
+	var fields []decodedField
+	for i, token := range parseJWTs(value) {
+		prefix := "jwt"
+		if i > 0 {
+			prefix = fmt.Sprintf("jwt[%d]", i)
+		}
+		field := func(name string, value string) {
+			fields = append(fields, decodedField{prefix + "." + name, value})
+		}
+
+		for _, name := range []string{"alg", "typ", "kid"} {
+			if s, ok := token.header[name].(string); ok {
+				field(name, s)
+			}
+		}
+		for _, name := range []string{"iss", "sub"} {
+			if s, ok := token.claims[name].(string); ok {
+				field(name, s)
+			}
+		}
+		// aud is either a single string or a list of them
+		switch aud := token.claims["aud"].(type) {
+		case string:
+			field("aud", aud)
+		case []interface{}:
+			for _, a := range aud {
+				if s, ok := a.(string); ok {
+					field("aud", s)
+				}
+			}
+		}
+		if exp, ok := token.expiry(); ok {
+			field("exp", exp.Format(time.RFC3339))
+		}
+	}
+	return fields
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func testJWT(header string, claims string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(header)) + "." + encode([]byte(claims)) + ".c2lnbmF0dXJl"
+}
+
+func TestDecodeJWT(t *testing.T) {
+	token := testJWT(`{"alg":"RS256","typ":"JWT","kid":"key-1"}`,
+		`{"iss":"https://issuer.example.com","sub":"svc-payments","aud":["api","billing"],"exp":1893456000}`)
+	second := testJWT(`{"alg":"HS256"}`, `{"sub":"other","aud":"api"}`)
+
+	expected := []decodedField{
+		{"jwt.alg", "RS256"},
+		{"jwt.typ", "JWT"},
+		{"jwt.kid", "key-1"},
+		{"jwt.iss", "https://issuer.example.com"},
+		{"jwt.sub", "svc-payments"},
+		{"jwt.aud", "api"},
+		{"jwt.aud", "billing"},
+		{"jwt.exp", "2030-01-01T00:00:00Z"},
+		{"jwt[1].alg", "HS256"},
+		{"jwt[1].sub", "other"},
+		{"jwt[1].aud", "api"},
+	}
+	if actual := decodeJWT("Bearer " + token + "\n" + second); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected fields %v, but got %v", expected, actual)
+	}
+
+	for _, value := range []string{"", "eyJ", "eyJhbGciOiJIUzI1NiJ9.eyJub3QganNvbg.sig", "not.a.token"} {
+		if fields := decodeJWT(value); fields != nil {
+			t.Errorf("Expected no fields for '%s', but got %v", value, fields)
+		}
+	}
+}
+
+func TestExpiryMatcherJWT(t *testing.T) {
+	now := time.Now().Unix()
+	expired := testJWT(`{"alg":"HS256"}`, `{"sub":"expired","exp":`+strconv.FormatInt(now-60, 10)+`}`)
+	valid := testJWT(`{"alg":"HS256"}`, `{"iss":"valid","exp":`+strconv.FormatInt(now+3600, 10)+`}`)
+	noExpiry := testJWT(`{"alg":"HS256"}`, `{"sub":"forever"}`)
+
+	matcher := newExpiryMatcher(0)
+
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{expired, []string{"JWT sub=expired"}},
+		{valid, nil},
+		{noExpiry, nil},
+	}
+
+	for _, tt := range tests {
+		var actual []string
+		for _, hit := range matcher.Match(tt.value) {
+			actual = append(actual, hit.Label)
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Expected expiring tokens %v, but got %v", tt.expected, actual)
+		}
+	}
+	if hits := newExpiryMatcher(2 * time.Hour).Match(valid); len(hits) != 1 || hits[0].Label != "JWT iss=valid" {
+		t.Errorf("Expected the token to expire within 2 hours, but got %v", hits)
+	}
+}
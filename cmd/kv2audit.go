@@ -0,0 +1,385 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(kv2AuditCmd)
+
+	kv2AuditCmd.Flags().IntVar(&kv2MaxVersions, "max-versions", 0, "Baseline max_versions of secrets and "+
+		"mounts, where 0 is Vault's default of 10. Not checked if not set")
+	kv2AuditCmd.Flags().BoolVar(&kv2CASRequired, "cas-required", false, "Baseline cas_required of secrets and "+
+		"mounts. Not checked if not set")
+	kv2AuditCmd.Flags().StringVar(&kv2DeleteVersionAfter, "delete-version-after", "", "Baseline "+
+		"delete_version_after of secrets and mounts, e.g. 90d, where 0s never deletes versions. Not checked if not set")
+	kv2AuditCmd.Flags().IntVar(&kv2VersionLimit, "version-limit", 100, "Report secrets keeping or allowed "+
+		"to keep more versions than this")
+}
+
+var (
+	kv2CASRequired        bool
+	kv2DeleteVersionAfter string
+	kv2MaxVersions        int
+	kv2VersionLimit       int
+)
+
+// defaultMaxVersions is the number of versions Vault keeps if max_versions is 0
+const defaultMaxVersions = 10
+
+var kv2AuditCmd = &cobra.Command{
+	Use:   "kv2-audit [flags] [search-path]",
+	Short: "Audit the metadata and settings of KV v2 secrets",
+	Long: `Audit the metadata and settings of KV v2 secrets, like tombstones and inconsistent max_versions
+
+Issues:
+  tombstone             every version of the secret is deleted or destroyed
+  max-versions          max_versions deviates from --max-versions
+  cas-required          cas_required deviates from --cas-required
+  delete-version-after  delete_version_after deviates from --delete-version-after
+  version-growth        more versions are kept or allowed than --version-limit
+
+Only metadata/ and the config of mounts are read, never secret values. The
+settings of mounts are reported once, and those of secrets only if they
+override the mount's. If no search-path is given, all available KV v2 stores
+are crawled.
+
+Exits with status 1 if any issue is found, so it can be used as a CI check`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if kv2MaxVersions < 0 {
+			return fmt.Errorf("--max-versions can't be negative")
+		}
+		if kv2VersionLimit <= 0 {
+			return fmt.Errorf("--version-limit must be positive")
+		}
+		if kv2DeleteVersionAfter != "" {
+			_, err := parseDuration(kv2DeleteVersionAfter)
+			return err
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var searchPath string
+		if len(args) == 1 {
+			searchPath = args[0]
+		}
+		opts := KV2AuditOptions{
+			CrawlingDelay:      crawlingDelay,
+			DeleteVersionAfter: kv2DeleteVersionAfter,
+			JSONOutput:         jsonOutput,
+			KvVersion:          kvVersion,
+			Timeout:            timeout,
+			VersionLimit:       kv2VersionLimit,
+		}
+		if cmd.Flags().Changed("max-versions") {
+			opts.MaxVersions = &kv2MaxVersions
+		}
+		if cmd.Flags().Changed("cas-required") {
+			opts.CASRequired = &kv2CASRequired
+		}
+		if !VaultKV2Audit(searchPath, opts) {
+			os.Exit(exitStatusNoMatch)
+		}
+	},
+	Example: "vault-kv-search kv2-audit --max-versions 20 --cas-required --delete-version-after 90d secret/",
+}
+
+// KV2AuditOptions holds the settings of a KV v2 metadata audit
+type KV2AuditOptions struct {
+	// CASRequired is the baseline cas_required, not checked if nil
+	CASRequired   *bool
+	CrawlingDelay int
+	// DeleteVersionAfter is the baseline delete_version_after, not checked if empty
+	DeleteVersionAfter string
+	JSONOutput         bool
+	KvVersion          int
+	// MaxVersions is the baseline max_versions, not checked if nil
+	MaxVersions *int
+	Timeout     int
+	// VersionLimit reports secrets keeping or allowed to keep more versions than this
+	VersionLimit int
+}
+
+// kv2Settings are the settings shared by the config of KV v2 mounts and the metadata of their secrets
+type kv2Settings struct {
+	maxVersions        int
+	casRequired        bool
+	deleteVersionAfter time.Duration
+}
+
+func parseKV2Settings(data map[string]interface{}) kv2Settings {
+	settings := kv2Settings{}
+	settings.maxVersions, _ = strconv.Atoi(fmt.Sprint(data["max_versions"]))
+	settings.casRequired, _ = data["cas_required"].(bool)
+	if s, ok := data["delete_version_after"].(string); ok {
+		settings.deleteVersionAfter, _ = time.ParseDuration(s)
+	}
+	return settings
+}
+
+type kv2Finding struct {
+	Search   string `json:"search"`
+	FullPath string `json:"path"`
+	Issue    string `json:"issue"`
+	Message  string `json:"message"`
+}
+
+// kv2Auditor collects the issues of KV v2 secrets and their mounts
+type kv2Auditor struct {
+	now                time.Time
+	readConfig         kv2ConfigReader
+	casRequired        *bool
+	deleteVersionAfter *time.Duration
+	maxVersions        *int
+	versionLimit       int
+
+	mu       sync.Mutex
+	checked  int
+	mounts   map[string]*kv2Mount
+	findings []kv2Finding
+}
+
+// kv2Mount holds the settings of a mount, read once by the first secret of the mount
+type kv2Mount struct {
+	once     sync.Once
+	settings kv2Settings
+}
+
+// kv2ConfigReader reads the config of a KV v2 mount, like secret/config
+type kv2ConfigReader func(path string) (map[string]interface{}, error)
+
+// VaultKV2Audit audits the metadata of the KV v2 secrets below searchPath, or
+// of all KV v2 stores if it is empty. It returns whether no issue was found.
+func VaultKV2Audit(searchPath string, opts KV2AuditOptions) bool {
+	client := newVaultClient(opts.Timeout)
+
+	auditor := &kv2Auditor{
+		now:          time.Now(),
+		casRequired:  opts.CASRequired,
+		maxVersions:  opts.MaxVersions,
+		versionLimit: opts.VersionLimit,
+		mounts:       map[string]*kv2Mount{},
+		readConfig: func(path string) (map[string]interface{}, error) {
+			secret, err := client.Logical().Read(path)
+			if err != nil || secret == nil {
+				return nil, err
+			}
+			return secret.Data, nil
+		},
+	}
+	if opts.DeleteVersionAfter != "" {
+		deleteVersionAfter, err := parseDuration(opts.DeleteVersionAfter)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(exitStatusError)
+		}
+		auditor.deleteVersionAfter = &deleteVersionAfter
+	}
+
+	vc := vaultClient{
+		crawlingDelay: opts.CrawlingDelay,
+		jsonOutput:    opts.JSONOutput,
+		logical:       client.Logical(),
+		metadataOnly:  true,
+		sys:           client.Sys(),
+		visit:         auditor.visit,
+		wg:            sync.WaitGroup{},
+	}
+
+	vc.crawl(kv2StartPaths(vc.startPaths(searchPath, opts.KvVersion)), "Auditing KV v2 metadata")
+
+	findings := auditor.sorted()
+	paths := map[string]bool{}
+	for _, finding := range findings {
+		paths[finding.FullPath] = true
+		vc.showKV2Finding(finding)
+	}
+	if !vc.jsonOutput {
+		fmt.Printf("%d issues in %d of %d audited secrets and mounts\n", len(findings), len(paths), auditor.checked)
+	}
+	return len(findings) == 0
+}
+
+// kv2StartPaths returns the start paths of KV v2 stores, warning about the skipped KV v1 ones
+func kv2StartPaths(startPaths []startPathInfo) []startPathInfo {
+	var kv2Paths []startPathInfo
+	for _, startPath := range startPaths {
+		if startPath.kvVersion < 2 {
+			_, _ = fmt.Fprintf(os.Stderr, "!!Warning!! %s is not a KV v2 store. Skipping.\n", startPath.path)
+			continue
+		}
+		kv2Paths = append(kv2Paths, startPath)
+	}
+	return kv2Paths
+}
+
+func (a *kv2Auditor) visit(dirEntry string, fullPath string, version int, metadata map[string]interface{}) {
+	mount, _, _ := strings.Cut(fullPath, "/")
+	mountSettings := a.mountSettings(mount)
+
+	var findings []kv2Finding
+	report := func(issue string, format string, args ...interface{}) {
+		findings = append(findings, kv2Finding{"kv2-audit", fullPath, issue, fmt.Sprintf(format, args...)})
+	}
+
+	versions, _ := metadata["versions"].(map[string]interface{})
+	deleted, destroyed := 0, 0
+	for _, v := range versions {
+		info, _ := v.(map[string]interface{})
+		switch {
+		case info["destroyed"] == true:
+			destroyed++
+		case versionDeleted(info, a.now):
+			deleted++
+		}
+	}
+	if len(versions) > 0 && deleted+destroyed == len(versions) {
+		report("tombstone", "all %d versions are deleted or destroyed, %d deleted and %d destroyed", len(versions),
+			deleted, destroyed)
+	}
+
+	// Settings of secrets only override the mount's if they are set
+	settings := parseKV2Settings(metadata)
+	if settings.maxVersions > 0 {
+		a.checkMaxVersions(settings.maxVersions, report)
+	}
+	if settings.casRequired && a.casRequired != nil && !*a.casRequired {
+		report("cas-required", "cas_required is true, the baseline is false")
+	}
+	if settings.deleteVersionAfter > 0 {
+		a.checkDeleteVersionAfter(settings.deleteVersionAfter, report)
+	}
+
+	maxVersions := settings.maxVersions
+	if maxVersions == 0 {
+		maxVersions = mountSettings.maxVersions
+	}
+	if maxVersions == 0 {
+		maxVersions = defaultMaxVersions
+	}
+	if len(versions) > a.versionLimit || settings.maxVersions > a.versionLimit {
+		report("version-growth", "%d versions kept, up to %d allowed, more than the limit of %d", len(versions),
+			maxVersions, a.versionLimit)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.checked++
+	a.findings = append(a.findings, findings...)
+}
+
+// versionDeleted reports whether a version was deleted by now. With
+// delete_version_after, Vault sets the deletion_time of live versions to when
+// they will be deleted.
+func versionDeleted(info map[string]interface{}, now time.Time) bool {
+	deletionTime, _ := info["deletion_time"].(string)
+	if deletionTime == "" {
+		return false
+	}
+	t, err := time.Parse(time.RFC3339Nano, deletionTime)
+	return err == nil && !t.After(now)
+}
+
+// mountSettings returns the config of a mount, reporting its deviations from the baseline when it is first
+// read. Only secrets of the same mount wait for the config to be read.
+func (a *kv2Auditor) mountSettings(mount string) kv2Settings {
+	a.mu.Lock()
+	m, ok := a.mounts[mount]
+	if !ok {
+		m = &kv2Mount{}
+		a.mounts[mount] = m
+	}
+	a.mu.Unlock()
+
+	m.once.Do(func() { m.settings = a.readMountSettings(mount) })
+	return m.settings
+}
+
+// readMountSettings reads the config of a mount and reports its deviations from the baseline
+func (a *kv2Auditor) readMountSettings(mount string) kv2Settings {
+	configPath := mount + "/config"
+	config, err := a.readConfig(configPath)
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "!!Warning!! can't read %s, auditing secrets without it: %s\n", configPath, err)
+	}
+	settings := parseKV2Settings(config)
+	if config == nil {
+		return settings
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.checked++
+	report := func(issue string, format string, args ...interface{}) {
+		a.findings = append(a.findings, kv2Finding{"kv2-audit", configPath, issue, fmt.Sprintf(format, args...)})
+	}
+	maxVersions := settings.maxVersions
+	if maxVersions == 0 {
+		maxVersions = defaultMaxVersions
+	}
+	a.checkMaxVersions(maxVersions, report)
+	if a.casRequired != nil && settings.casRequired != *a.casRequired {
+		report("cas-required", "cas_required is %t, the baseline is %t", settings.casRequired, *a.casRequired)
+	}
+	a.checkDeleteVersionAfter(settings.deleteVersionAfter, report)
+	if maxVersions > a.versionLimit {
+		report("version-growth", "up to %d versions allowed, more than the limit of %d", maxVersions, a.versionLimit)
+	}
+	return settings
+}
+
+func (a *kv2Auditor) checkMaxVersions(maxVersions int, report func(string, string, ...interface{})) {
+	if a.maxVersions == nil {
+		return
+	}
+	baseline := *a.maxVersions
+	if baseline == 0 {
+		baseline = defaultMaxVersions
+	}
+	if maxVersions != baseline {
+		report("max-versions", "max_versions is %d, the baseline is %d", maxVersions, baseline)
+	}
+}
+
+func (a *kv2Auditor) checkDeleteVersionAfter(deleteVersionAfter time.Duration,
+	report func(string, string, ...interface{})) {
+	if a.deleteVersionAfter != nil && deleteVersionAfter != *a.deleteVersionAfter {
+		report("delete-version-after", "delete_version_after is %s, the baseline is %s", deleteVersionAfter,
+			*a.deleteVersionAfter)
+	}
+}
+
+// sorted returns the findings ordered by path and issue
+func (a *kv2Auditor) sorted() []kv2Finding {
+	sort.Slice(a.findings, func(i, j int) bool {
+		if a.findings[i].FullPath != a.findings[j].FullPath {
+			return a.findings[i].FullPath < a.findings[j].FullPath
+		}
+		return a.findings[i].Issue < a.findings[j].Issue
+	})
+	return a.findings
+}
+
+func (vc *vaultClient) showKV2Finding(finding kv2Finding) {
+	if vc.jsonOutput {
+		findingJSON, err := json.Marshal(finding)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "can't marshal JSON: %s\n", err)
+			return
+		}
+		fmt.Println(string(findingJSON))
+	} else {
+		fmt.Printf("KV v2 issue:\n\tPath: %s\n\tIssue: %s\n\tMessage: %s\n\n", finding.FullPath, finding.Issue,
+			finding.Message)
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestKV2Auditor(t *testing.T) {
+	maxVersions, casRequired, deleteVersionAfter := 20, true, 90*24*time.Hour
+	auditor := &kv2Auditor{
+		now:                time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+		casRequired:        &casRequired,
+		deleteVersionAfter: &deleteVersionAfter,
+		maxVersions:        &maxVersions,
+		versionLimit:       50,
+		mounts:             map[string]*kv2Mount{},
+		readConfig: func(path string) (map[string]interface{}, error) {
+			if path != "kv/config" {
+				t.Errorf("Expected the config of kv to be read, but got %s", path)
+			}
+			return map[string]interface{}{
+				"max_versions":         json.Number("0"),
+				"cas_required":         true,
+				"delete_version_after": "2160h0m0s",
+			}, nil
+		},
+	}
+
+	versions := func(states ...string) map[string]interface{} {
+		result := map[string]interface{}{}
+		for i, state := range states {
+			version := map[string]interface{}{"deletion_time": "", "destroyed": state == "destroyed"}
+			switch state {
+			case "deleted":
+				version["deletion_time"] = "2024-01-01T00:00:00Z"
+			case "expiring":
+				// Versions of secrets with delete_version_after are deleted in the future
+				version["deletion_time"] = "2025-08-30T00:00:00.123456Z"
+			}
+			result[strconv.Itoa(i+1)] = version
+		}
+		return result
+	}
+
+	auditor.visit("ok", "kv/app/ok", 2, map[string]interface{}{
+		"max_versions": json.Number("0"), "cas_required": false, "delete_version_after": "0s",
+		"versions": versions("live", "deleted"),
+	})
+	auditor.visit("gone", "kv/app/gone", 2, map[string]interface{}{
+		"max_versions": json.Number("0"), "cas_required": false, "delete_version_after": "0s",
+		"versions": versions("deleted", "destroyed"),
+	})
+	auditor.visit("expiring", "kv/app/expiring", 2, map[string]interface{}{
+		"max_versions": json.Number("0"), "cas_required": false, "delete_version_after": "0s",
+		"versions": versions("expiring", "expiring"),
+	})
+	auditor.visit("custom", "kv/app/custom", 2, map[string]interface{}{
+		"max_versions": json.Number("100"), "cas_required": false, "delete_version_after": "1h0m0s",
+		"versions": versions("live"),
+	})
+
+	var actual []string
+	for _, finding := range auditor.sorted() {
+		actual = append(actual, finding.FullPath+" "+finding.Issue)
+	}
+	expected := []string{
+		"kv/app/custom delete-version-after",
+		"kv/app/custom max-versions",
+		"kv/app/custom version-growth",
+		"kv/app/gone tombstone",
+		"kv/config max-versions",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected findings %v, but got %v", expected, actual)
+	}
+	if auditor.checked != 5 {
+		t.Errorf("Expected 4 secrets and 1 mount to be audited, but got %d", auditor.checked)
+	}
+}
+
+func TestKV2AuditorReadsConfigsConcurrently(t *testing.T) {
+	otherVisited := make(chan struct{})
+	auditor := &kv2Auditor{
+		versionLimit: 50,
+		mounts:       map[string]*kv2Mount{},
+		readConfig: func(path string) (map[string]interface{}, error) {
+			if path == "slow/config" {
+				// Secrets of other mounts must not wait for this read
+				select {
+				case <-otherVisited:
+				case <-time.After(5 * time.Second):
+					t.Error("Expected other mounts to be audited while a config is read")
+				}
+			}
+			return map[string]interface{}{"max_versions": json.Number("0")}, nil
+		},
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		auditor.visit("app", "slow/app", 2, map[string]interface{}{})
+	}()
+	// Let the first visit start reading the config of slow
+	time.Sleep(50 * time.Millisecond)
+	auditor.visit("app", "fast/app", 2, map[string]interface{}{})
+	close(otherVisited)
+	<-done
+
+	if auditor.checked != 4 {
+		t.Errorf("Expected 2 secrets and 2 mounts to be audited, but got %d", auditor.checked)
+	}
+}
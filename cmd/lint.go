@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVar(&lintConfig, "config", "", "JSON file enabling, disabling and configuring lint "+
+		"rules, e.g. {\"rules\": {\"too-many-keys\": {\"severity\": \"error\", \"max\": 20}}}")
+	lintCmd.Flags().StringSliceVar(&lintDisable, "disable", nil, fmt.Sprintf("Lint rules to disable. Choices "+
+		"are %v", lintRuleIDs()))
+}
+
+var (
+	lintConfig  string
+	lintDisable []string
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [flags] [search-path]",
+	Short: "Check secrets for hygiene issues",
+	Long: `Check secrets for hygiene issues, like empty values, placeholders and inconsistent key names
+
+Rules:
+  empty-value      values that are empty
+  whitespace       values with leading or trailing whitespace or newlines
+  placeholder      placeholder values, like TODO or changeme
+  key-casing       keys spelled differently in sibling secrets, like DB_HOST and db_host
+  key-spaces       keys containing whitespace
+  too-many-keys    secrets with more than 50 keys
+
+Rules can be disabled, and their severity, info, warning or error, changed
+with --config. Values are never shown. If no search-path is given, all
+available KV stores are crawled.
+
+Exits with status 1 if any finding has the severity error, so it can be used
+as a CI check`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		_, err := loadLintRules(lintConfig, lintDisable)
+		return err
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var searchPath string
+		if len(args) == 1 {
+			searchPath = args[0]
+		}
+		clean := VaultLint(searchPath, LintOptions{
+			Config:        lintConfig,
+			CrawlingDelay: crawlingDelay,
+			Disable:       lintDisable,
+			JSONOutput:    jsonOutput,
+			KvVersion:     kvVersion,
+			Timeout:       timeout,
+		})
+		if !clean {
+			os.Exit(exitStatusNoMatch)
+		}
+	},
+	Example: "vault-kv-search lint --disable key-casing secret/",
+}
+
+// LintOptions holds the settings of a hygiene check
+type LintOptions struct {
+	// Config is a JSON file configuring the lint rules
+	Config        string
+	CrawlingDelay int
+	// Disable are the IDs of lint rules to disable
+	Disable    []string
+	JSONOutput bool
+	KvVersion  int
+	Timeout    int
+}
+
+// lintRule is the configuration of a hygiene check. Max is the maximum number
+// of keys of too-many-keys and Values the placeholders of placeholder.
+type lintRule struct {
+	Enabled  bool     `json:"enabled"`
+	Severity string   `json:"severity"`
+	Max      int      `json:"max,omitempty"`
+	Values   []string `json:"values,omitempty"`
+}
+
+const (
+	lintEmptyValue  = "empty-value"
+	lintWhitespace  = "whitespace"
+	lintPlaceholder = "placeholder"
+	lintKeyCasing   = "key-casing"
+	lintKeySpaces   = "key-spaces"
+	lintTooManyKeys = "too-many-keys"
+)
+
+// lintSeverities are the valid severities, from least to most severe
+var lintSeverities = []string{"info", "warning", "error"}
+
+func defaultLintRules() map[string]lintRule {
+	return map[string]lintRule{
+		lintEmptyValue: {Enabled: true, Severity: "warning"},
+		lintWhitespace: {Enabled: true, Severity: "warning"},
+		lintPlaceholder: {Enabled: true, Severity: "error", Values: []string{"todo", "tbd", "fixme", "changeme",
+			"change_me", "change-me", "replaceme", "replace_me", "placeholder", "xxx", "xxxx", "dummy", "<secret>",
+			"<password>"}},
+		lintKeyCasing:   {Enabled: true, Severity: "warning"},
+		lintKeySpaces:   {Enabled: true, Severity: "warning"},
+		lintTooManyKeys: {Enabled: true, Severity: "warning", Max: 50},
+	}
+}
+
+// lintRuleIDs returns the sorted IDs of the lint rules
+func lintRuleIDs() []string {
+	var ids []string
+	for id := range defaultLintRules() {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// loadLintRules returns the default lint rules, overridden by the rules of
+// configPath, if given, and without the disabled ones
+func loadLintRules(configPath string, disable []string) (map[string]lintRule, error) {
+	rules := defaultLintRules()
+
+	if configPath != "" {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read lint config: %w", err)
+		}
+		var config struct {
+			Rules map[string]json.RawMessage `json:"rules"`
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("failed to parse lint config %s: %w", configPath, err)
+		}
+		for id, raw := range config.Rules {
+			rule, ok := rules[id]
+			if !ok {
+				return nil, fmt.Errorf("%s is not a valid lint rule. Choices are %v", id, lintRuleIDs())
+			}
+			// Only the settings given in the config override the defaults
+			if err := json.Unmarshal(raw, &rule); err != nil {
+				return nil, fmt.Errorf("failed to parse lint rule %s in %s: %w", id, configPath, err)
+			}
+			rules[id] = rule
+		}
+	}
+
+	for _, id := range disable {
+		rule, ok := rules[id]
+		if !ok {
+			return nil, fmt.Errorf("%s is not a valid lint rule. Choices are %v", id, lintRuleIDs())
+		}
+		rule.Enabled = false
+		rules[id] = rule
+	}
+
+	for id, rule := range rules {
+		if severityRank(rule.Severity) < 0 {
+			return nil, fmt.Errorf("%s is not a valid severity of lint rule %s. Choices are %v", rule.Severity, id,
+				lintSeverities)
+		}
+		if id == lintTooManyKeys && rule.Max <= 0 {
+			return nil, fmt.Errorf("max of lint rule %s must be positive", id)
+		}
+	}
+	return rules, nil
+}
+
+func severityRank(severity string) int {
+	for i, s := range lintSeverities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+type lintFinding struct {
+	Search   string `json:"search"`
+	FullPath string `json:"path"`
+	Key      string `json:"key,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// linter collects the findings of the lint rules
+type linter struct {
+	rules        map[string]lintRule
+	placeholders map[string]struct{}
+
+	mu       sync.Mutex
+	checked  int
+	findings []lintFinding
+	// keySpellings maps directories to normalized key names to their spellings to the secrets using them
+	keySpellings map[string]map[string]map[string][]string
+}
+
+func newLinter(rules map[string]lintRule) *linter {
+	placeholders := map[string]struct{}{}
+	for _, value := range rules[lintPlaceholder].Values {
+		placeholders[strings.ToLower(value)] = struct{}{}
+	}
+	return &linter{rules: rules, placeholders: placeholders, keySpellings: map[string]map[string]map[string][]string{}}
+}
+
+// VaultLint checks the secrets below searchPath, or all KV stores if it is
+// empty, for hygiene issues. It returns whether no finding has the severity
+// error.
+func VaultLint(searchPath string, opts LintOptions) bool {
+	client := newVaultClient(opts.Timeout)
+
+	rules, err := loadLintRules(opts.Config, opts.Disable)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(exitStatusError)
+	}
+	l := newLinter(rules)
+
+	vc := vaultClient{
+		crawlingDelay: opts.CrawlingDelay,
+		jsonOutput:    opts.JSONOutput,
+		logical:       client.Logical(),
+		sys:           client.Sys(),
+		visit:         l.visit,
+		wg:            sync.WaitGroup{},
+	}
+
+	vc.crawl(vc.startPaths(searchPath, opts.KvVersion), "Linting secrets")
+
+	counts := map[string]int{}
+	for _, finding := range l.sorted() {
+		counts[finding.Severity]++
+		vc.showLintFinding(finding)
+	}
+	if !vc.jsonOutput {
+		fmt.Printf("%d errors, %d warnings and %d infos in %d checked secrets\n", counts["error"], counts["warning"],
+			counts["info"], l.checked)
+	}
+	return counts["error"] == 0
+}
+
+func (l *linter) visit(dirEntry string, fullPath string, version int, data map[string]interface{}) {
+	var findings []lintFinding
+	report := func(id string, key string, message string) {
+		if rule := l.rules[id]; rule.Enabled {
+			findings = append(findings, lintFinding{"lint", fullPath, key, id, rule.Severity, message})
+		}
+	}
+
+	walkValues(version, data, func(v secretValue) {
+		if strings.ContainsFunc(v.path, unicode.IsSpace) {
+			report(lintKeySpaces, v.path, "key contains whitespace")
+		}
+		if v.value == "" {
+			report(lintEmptyValue, v.path, "empty value")
+			return
+		}
+		if _, ok := l.placeholders[strings.ToLower(strings.TrimSpace(v.value))]; ok {
+			report(lintPlaceholder, v.path, "placeholder value")
+		}
+		switch {
+		case strings.TrimSpace(v.value) == "":
+			report(lintWhitespace, v.path, "value is only whitespace")
+		case strings.TrimLeftFunc(v.value, unicode.IsSpace) != v.value:
+			report(lintWhitespace, v.path, "leading whitespace")
+		case strings.HasSuffix(v.value, "\n"):
+			report(lintWhitespace, v.path, "trailing newline")
+		case strings.TrimRightFunc(v.value, unicode.IsSpace) != v.value:
+			report(lintWhitespace, v.path, "trailing whitespace")
+		}
+	})
+
+	if version > 1 {
+		data, _ = data["data"].(map[string]interface{})
+	}
+	if rule := l.rules[lintTooManyKeys]; len(data) > rule.Max {
+		report(lintTooManyKeys, "", fmt.Sprintf("%d keys, more than %d", len(data), rule.Max))
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.checked++
+	l.findings = append(l.findings, findings...)
+	dir := path.Dir(strings.TrimSuffix(fullPath, "/"))
+	if l.keySpellings[dir] == nil {
+		l.keySpellings[dir] = map[string]map[string][]string{}
+	}
+	for key := range data {
+		normalized := normalizeKey(key)
+		if l.keySpellings[dir][normalized] == nil {
+			l.keySpellings[dir][normalized] = map[string][]string{}
+		}
+		l.keySpellings[dir][normalized][key] = append(l.keySpellings[dir][normalized][key], fullPath)
+	}
+}
+
+// normalizeKey returns key in lower case without separators, so that DB_HOST, db-host and dbHost are the same
+func normalizeKey(key string) string {
+	return strings.NewReplacer("_", "", "-", "", ".", "", " ", "").Replace(strings.ToLower(key))
+}
+
+// casingFindings reports the keys of secrets spelled differently than the
+// same key in most of their sibling secrets
+func (l *linter) casingFindings() []lintFinding {
+	rule := l.rules[lintKeyCasing]
+	if !rule.Enabled {
+		return nil
+	}
+
+	var findings []lintFinding
+	for _, keys := range l.keySpellings {
+		for _, spellings := range keys {
+			if len(spellings) < 2 {
+				continue
+			}
+			// The most common spelling is the expected one, or the first in sort order on a tie
+			var names []string
+			for name := range spellings {
+				names = append(names, name)
+			}
+			sort.Slice(names, func(i, j int) bool {
+				if len(spellings[names[i]]) != len(spellings[names[j]]) {
+					return len(spellings[names[i]]) > len(spellings[names[j]])
+				}
+				return names[i] < names[j]
+			})
+			expected := names[0]
+			for _, name := range names[1:] {
+				for _, fullPath := range spellings[name] {
+					findings = append(findings, lintFinding{"lint", fullPath, name, lintKeyCasing, rule.Severity,
+						fmt.Sprintf("spelled %s in %d sibling secrets", expected, len(spellings[expected]))})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// sorted returns all findings ordered by secret path, key and rule
+func (l *linter) sorted() []lintFinding {
+	findings := append(append([]lintFinding(nil), l.findings...), l.casingFindings()...)
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].FullPath != findings[j].FullPath {
+			return findings[i].FullPath < findings[j].FullPath
+		}
+		if findings[i].Key != findings[j].Key {
+			return findings[i].Key < findings[j].Key
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+	return findings
+}
+
+func (vc *vaultClient) showLintFinding(finding lintFinding) {
+	if vc.jsonOutput {
+		findingJSON, err := json.Marshal(finding)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "can't marshal JSON: %s\n", err)
+			return
+		}
+		fmt.Println(string(findingJSON))
+	} else {
+		fmt.Printf("Lint %s:\n\tSecret: %s\n", finding.Severity, finding.FullPath)
+		if finding.Key != "" {
+			fmt.Printf("\tKey: %s\n", finding.Key)
+		}
+		fmt.Printf("\tRule: %s\n\tMessage: %s\n\n", finding.Rule, finding.Message)
+	}
+}
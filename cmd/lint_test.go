@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadLintRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lint.json")
+	config := `{"rules": {"too-many-keys": {"severity": "error", "max": 2}, "empty-value": {"enabled": false}}}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	rules, err := loadLintRules(path, []string{"key-spaces"})
+	if err != nil {
+		t.Fatalf("Failed to load rules: %v", err)
+	}
+	expected := lintRule{Enabled: true, Severity: "error", Max: 2}
+	if !reflect.DeepEqual(rules[lintTooManyKeys], expected) {
+		t.Errorf("Expected %s to be %v, but got %v", lintTooManyKeys, expected, rules[lintTooManyKeys])
+	}
+	if rules[lintEmptyValue].Enabled || rules[lintKeySpaces].Enabled || !rules[lintWhitespace].Enabled {
+		t.Errorf("Unexpected enabled rules %v", rules)
+	}
+	if len(rules[lintPlaceholder].Values) == 0 {
+		t.Errorf("Expected the default placeholders to be kept")
+	}
+
+	for _, config := range []string{`{"rules": {"unknown": {}}}`, `{"rules": {"whitespace": {"severity": "fatal"}}}`,
+		`{"rules": {"too-many-keys": {"max": 0}}}`} {
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		if _, err := loadLintRules(path, nil); err == nil {
+			t.Errorf("Expected config %s to be rejected", config)
+		}
+	}
+	if _, err := loadLintRules("", []string{"unknown"}); err == nil {
+		t.Errorf("Expected disabling an unknown rule to be rejected")
+	}
+}
+
+func TestLinter(t *testing.T) {
+	rules, err := loadLintRules("", nil)
+	if err != nil {
+		t.Fatalf("Failed to load rules: %v", err)
+	}
+	rule := rules[lintTooManyKeys]
+	rule.Max = 3
+	rules[lintTooManyKeys] = rule
+	l := newLinter(rules)
+
+	l.visit("app", "kv/prod/app", 2, map[string]interface{}{
+		"data": map[string]interface{}{
+			"DB_HOST":  "db",
+			"password": "ChangeMe",
+			"token":    " abc\n",
+			"api key":  "",
+		},
+	})
+	l.visit("worker", "kv/prod/worker", 1, map[string]interface{}{
+		"db_host": "db",
+		"cert":    "-----BEGIN CERTIFICATE-----\n",
+	})
+	l.visit("cron", "kv/prod/cron", 1, map[string]interface{}{"db_host": "db"})
+	l.visit("app", "kv/dev/app", 1, map[string]interface{}{"DB_HOST": "db"})
+
+	var actual []lintFinding
+	for _, finding := range l.sorted() {
+		finding.Message = ""
+		actual = append(actual, finding)
+	}
+	expected := []lintFinding{
+		{"lint", "kv/prod/app", "", lintTooManyKeys, "warning", ""},
+		{"lint", "kv/prod/app", "DB_HOST", lintKeyCasing, "warning", ""},
+		{"lint", "kv/prod/app", "api key", lintEmptyValue, "warning", ""},
+		{"lint", "kv/prod/app", "api key", lintKeySpaces, "warning", ""},
+		{"lint", "kv/prod/app", "password", lintPlaceholder, "error", ""},
+		{"lint", "kv/prod/app", "token", lintWhitespace, "warning", ""},
+		{"lint", "kv/prod/worker", "cert", lintWhitespace, "warning", ""},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected findings %v, but got %v", expected, actual)
+	}
+	if l.checked != 4 {
+		t.Errorf("Expected 4 checked secrets, but got %d", l.checked)
+	}
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Matcher reports whether a key, value or path matches the search pattern.
+// Matchers are compiled once at startup and shared by all crawling goroutines.
+type Matcher interface {
+	// Match returns one hit per search term found in s, or nil if there is none
+	Match(s string) []Hit
+}
+
+// Hit describes a match. Term and Label are only set by matchers searching
+// for several terms at once, Distance only by fuzzy matching and Rule only by
+// credential detection. Expiry matching sets Label to the subject of the
+// certificate or token and Expires to its expiry. Column is the 1-based
+// column of the match, if the matcher knows it.
+type Hit struct {
+	Term     string
+	Label    string
+	Distance *int
+	Rule     string
+	Expires  string
+	Column   int
+}
+
+// hitIf returns a single hit if matched is true
+func hitIf(matched bool) []Hit {
+	if matched {
+		return []Hit{{}}
+	}
+	return nil
+}
+
+// normalizedString is a string normalized for matching, like case folded,
+// that knows the offsets of its bytes in the original string
+type normalizedString struct {
+	original string
+	text     string
+	// offsets maps byte offsets of text to byte offsets of original, nil if text is original
+	offsets []int
+}
+
+// keepString leaves a string as it is for case-sensitive matching
+func keepString(s string) normalizedString {
+	return normalizedString{s, s, nil}
+}
+
+// normalizeString applies normalize, like foldString, to every NFKC segment of
+// s, so that offsets in the result can be mapped back to s
+func normalizeString(s string, normalize func(string) string) normalizedString {
+	if normalize(s) == s {
+		return keepString(s)
+	}
+
+	var text strings.Builder
+	offsets := make([]int, 0, len(s)+1)
+	for i := 0; i < len(s); {
+		n := norm.NFKC.NextBoundaryInString(s[i:], true)
+		if n <= 0 {
+			n = len(s) - i
+		}
+		segment := s[i : i+n]
+		normalized := normalize(segment)
+		text.WriteString(normalized)
+		for j := range len(normalized) {
+			// Bytes of a changed segment point at its start
+			if normalized != segment {
+				j = 0
+			}
+			offsets = append(offsets, i+j)
+		}
+		i += n
+	}
+	offsets = append(offsets, len(s))
+	return normalizedString{s, text.String(), offsets}
+}
+
+// hitAt returns a single hit at byte offset i of the normalized text, or nil if
+// i is negative. Its column is that of the original string.
+func (s normalizedString) hitAt(i int) []Hit {
+	if i < 0 {
+		return nil
+	}
+	if s.offsets != nil {
+		i = s.offsets[i]
+	}
+	return []Hit{{Column: utf8.RuneCountInString(s.original[:i]) + 1}}
+}
+
+// matchModes are the choices of the --match flag
+var matchModes = []string{"substring", "exact", "glob", "word", "regex", "fuzzy"}
+
+// resolveMatchMode returns the match mode to use, where --regex is a shorthand for --match regex
+func resolveMatchMode(mode string, useRegex bool) string {
+	if useRegex {
+		return "regex"
+	}
+	if mode == "" {
+		return "substring"
+	}
+	return mode
+}
+
+// newMatcher compiles pattern for the given match mode. With ignoreCase,
+// both the pattern and the matched strings are Unicode case folded and NFKC
+// normalized, so e.g. "ＡＢＣ" and "abc" match. Fuzzy matching is configured
+// with fuzzy.
+func newMatcher(mode string, pattern string, ignoreCase bool, fuzzy FuzzyOptions) (Matcher, error) {
+	normalize := keepString
+	if ignoreCase {
+		normalize = func(s string) normalizedString { return normalizeString(s, foldString) }
+	}
+
+	switch mode {
+	case "substring":
+		return substringMatcher{normalize(pattern).text, normalize}, nil
+	case "exact":
+		return exactMatcher{normalize(pattern).text, normalize}, nil
+	case "word":
+		if pattern == "" {
+			return nil, fmt.Errorf("word matching needs a non-empty pattern")
+		}
+		return wordMatcher{normalize(pattern).text, normalize}, nil
+	case "glob":
+		re, err := regexp.Compile("(?s)" + globToRegexp(normalize(pattern).text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+		return regexMatcher{re, normalize}, nil
+	case "fuzzy":
+		return newFuzzyMatcher(pattern, ignoreCase, fuzzy)
+	case "regex":
+		if ignoreCase {
+			// Folding the pattern itself would change escapes like \S, so rely on the regex engine instead
+			pattern = "(?i)" + norm.NFKC.String(pattern)
+			normalize = func(s string) normalizedString { return normalizeString(s, norm.NFKC.String) }
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+		}
+		return regexMatcher{re, normalize}, nil
+	default:
+		return nil, fmt.Errorf("%s is not a valid match mode. Choices are %v", mode, matchModes)
+	}
+}
+
+// foldString applies Unicode case folding and NFKC normalization
+func foldString(s string) string {
+	// A Caser can't be shared between goroutines, so create one per call
+	return norm.NFKC.String(cases.Fold().String(s))
+}
+
+type substringMatcher struct {
+	pattern   string
+	normalize func(string) normalizedString
+}
+
+func (m substringMatcher) Match(s string) []Hit {
+	normalized := m.normalize(s)
+	return normalized.hitAt(strings.Index(normalized.text, m.pattern))
+}
+
+type exactMatcher struct {
+	pattern   string
+	normalize func(string) normalizedString
+}
+
+func (m exactMatcher) Match(s string) []Hit {
+	normalized := m.normalize(s)
+	if normalized.text != m.pattern {
+		return nil
+	}
+	return normalized.hitAt(0)
+}
+
+type regexMatcher struct {
+	re        *regexp.Regexp
+	normalize func(string) normalizedString
+}
+
+func (m regexMatcher) Match(s string) []Hit {
+	normalized := m.normalize(s)
+	loc := m.re.FindStringIndex(normalized.text)
+	if loc == nil {
+		return nil
+	}
+	return normalized.hitAt(loc[0])
+}
+
+// wordMatcher matches the pattern only where it isn't surrounded by letters,
+// digits or underscores. Unlike \b in regexes, this works for any script.
+type wordMatcher struct {
+	pattern   string
+	normalize func(string) normalizedString
+}
+
+func (m wordMatcher) Match(s string) []Hit {
+	normalized := m.normalize(s)
+	return normalized.hitAt(m.indexWord(normalized.text))
+}
+
+// indexWord returns the byte offset of the first whole word occurrence of the pattern in s, or -1
+func (m wordMatcher) indexWord(s string) int {
+	for offset := 0; offset <= len(s); {
+		i := strings.Index(s[offset:], m.pattern)
+		if i < 0 {
+			return -1
+		}
+		start, end := offset+i, offset+i+len(m.pattern)
+
+		before, _ := utf8.DecodeLastRuneInString(s[:start])
+		after, _ := utf8.DecodeRuneInString(s[end:])
+		if (start == 0 || !isWordRune(before)) && (end == len(s) || !isWordRune(after)) {
+			return start
+		}
+
+		_, size := utf8.DecodeRuneInString(s[start:])
+		offset = start + size
+	}
+	return -1
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
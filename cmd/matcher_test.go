@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		mode       string
+		pattern    string
+		ignoreCase bool
+		input      string
+		expected   bool
+	}{
+		{"substring", "data", false, "foo-data-bar", true},
+		{"substring", "DATA", false, "foo-data-bar", false},
+		{"substring", "DATA", true, "foo-data-bar", true},
+		{"exact", "data", false, "foo-data-bar", false},
+		{"exact", "data", false, "data", true},
+		{"exact", "straße", true, "STRASSE", true},
+		{"glob", "foo-*-bar", false, "foo-data-bar", true},
+		{"glob", "foo-?-bar", false, "foo-data-bar", false},
+		{"glob", "db-[0-9]", false, "db-7", true},
+		{"glob", "FOO-*", true, "foo-data-bar", true},
+		{"word", "data", false, "foo-data-bar", true},
+		{"word", "data", false, "foo_data_bar", false},
+		{"word", "data", false, "metadata data", true},
+		{"word", "naïve", false, "le naïveté", false},
+		{"word", "naïve", false, "so naïve!", true},
+		{"regex", "^foo-", false, "foo-data-bar", true},
+		{"regex", "^FOO-", true, "foo-data-bar", true},
+		{"regex", `\S+-bar$`, true, "FOO-DATA-BAR", true},
+		{"substring", "abc", true, "ＡＢＣ", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.pattern+"/"+tt.input, func(t *testing.T) {
+			matcher, err := newMatcher(tt.mode, tt.pattern, tt.ignoreCase, defaultFuzzyOptions)
+			if err != nil {
+				t.Fatalf("Failed to create matcher: %v", err)
+			}
+			if actual := matcher.Match(tt.input) != nil; actual != tt.expected {
+				t.Errorf("Expected %v, but got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMatcherColumn(t *testing.T) {
+	tests := []struct {
+		mode       string
+		pattern    string
+		ignoreCase bool
+		input      string
+		expected   int
+	}{
+		{"substring", "data", false, "foo-data-bar", 5},
+		{"substring", "bar", false, "naïve bar", 7},
+		{"exact", "data", false, "data", 1},
+		{"glob", "*-bar", false, "foo-data-bar", 1},
+		{"word", "data", false, "metadata data", 10},
+		{"regex", `d\w+`, false, "foo-data-bar", 5},
+		// ß folds to ss and ﬁ normalizes to fi, but columns point into the original value
+		{"substring", "data", true, "STRAßE DATA", 8},
+		{"word", "data", true, "ﬁle data", 5},
+		{"regex", "data", true, "ﬁle DATA", 5},
+		{"substring", "ße", true, "STRAßE", 5},
+	}
+
+	for _, tt := range tests {
+		matcher, err := newMatcher(tt.mode, tt.pattern, tt.ignoreCase, defaultFuzzyOptions)
+		if err != nil {
+			t.Fatalf("Failed to create matcher: %v", err)
+		}
+		if hits := matcher.Match(tt.input); len(hits) != 1 || hits[0].Column != tt.expected {
+			t.Errorf("Expected %s match of '%s' in '%s' at column %d, but got %v", tt.mode, tt.pattern, tt.input,
+				tt.expected, hits)
+		}
+	}
+}
+
+func TestMatcherErrors(t *testing.T) {
+	if _, err := newMatcher("regex", "foo(", false, defaultFuzzyOptions); err == nil {
+		t.Error("Expected invalid regex to be rejected")
+	}
+	if _, err := newMatcher("soundex", "foo", false, defaultFuzzyOptions); err == nil {
+		t.Error("Expected unknown match mode to be rejected")
+	}
+	if _, err := newMatcher("word", "", false, defaultFuzzyOptions); err == nil {
+		t.Error("Expected empty word to be rejected")
+	}
+	if _, err := newMatcher("fuzzy", "foo", false, FuzzyOptions{MaxDistance: 2}); err == nil {
+		t.Error("Expected the fuzzy options to be used")
+	}
+}
+
+func TestFuzzyMatcherOptions(t *testing.T) {
+	matcher, err := newMatcher("fuzzy", "secret", false, FuzzyOptions{MaxDistance: 0, MaxLength: 256})
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+	if matcher.Match("secret1") != nil {
+		t.Error("Expected a max distance of 0 to only match exact values")
+	}
+	if matcher.Match("secret") == nil {
+		t.Error("Expected an exact value to match")
+	}
+}
+
+func TestResolveMatchMode(t *testing.T) {
+	if mode := resolveMatchMode("substring", true); mode != "regex" {
+		t.Errorf("Expected --regex to select regex mode, but got %s", mode)
+	}
+	if mode := resolveMatchMode("", false); mode != "substring" {
+		t.Errorf("Expected substring to be the default mode, but got %s", mode)
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(policiesCmd)
+}
+
+var policiesCmd = &cobra.Command{
+	Use:   "policies [flags] substring",
+	Short: "Search ACL policies",
+	Long: `Search the HCL of every ACL policy for substring
+
+Each policy listed under sys/policies/acl is fetched and matched line by line,
+reporting the policy name and line number of every match`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		_, err := searchMatcher(args[0])
+		return err
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		matcher, _ := searchMatcher(args[0])
+		if VaultPolicySearch(args[0], matcher, jsonOutput, timeout) == 0 {
+			os.Exit(exitStatusNoMatch)
+		}
+	},
+	Example: "vault-kv-search policies secret/data/prod",
+}
+
+type policyMatched struct {
+	Search   string `json:"search"`
+	FullPath string `json:"path"`
+	Line     int    `json:"line"`
+	Value    string `json:"value"`
+}
+
+// VaultPolicySearch searches every ACL policy for searchString. It returns the number of matches.
+func VaultPolicySearch(searchString string, matcher Matcher, jsonOutput bool, timeoutSeconds int) int {
+	client := newVaultClient(timeoutSeconds)
+
+	vc := vaultClient{
+		jsonOutput:   jsonOutput,
+		matcher:      matcher,
+		searchString: searchString,
+		sys:          client.Sys(),
+	}
+
+	if !vc.jsonOutput {
+		fmt.Printf("Searching for substring '%s' against: [policy]\n", searchString)
+	}
+
+	policies, err := vc.sys.ListPolicies()
+	if err != nil {
+		fmt.Println(fmt.Errorf("failed to list policies: %w", err))
+		os.Exit(exitStatusError)
+	}
+
+	for _, name := range policies {
+		policy, err := vc.sys.GetPolicy(name)
+		if err != nil {
+			fmt.Println(fmt.Errorf("failed to read policy %s: %w", name, err))
+			os.Exit(exitStatusError)
+		}
+		vc.policyMatch(name, policy)
+	}
+	return vc.results
+}
+
+func (vc *vaultClient) policyMatch(name string, policy string) {
+	for i, line := range strings.Split(policy, "\n") {
+		if vc.matcher.Match(line) != nil {
+			match := policyMatched{"policy", "sys/policies/acl/" + name, i + 1, line}
+			vc.results++
+			vc.showPolicyMatch(match)
+		}
+	}
+}
+
+func (vc *vaultClient) showPolicyMatch(policy policyMatched) {
+	if vc.jsonOutput {
+		policyJSON, err := json.Marshal(policy)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "can't marshal JSON: %s\n", err)
+			return
+		}
+		fmt.Println(string(policyJSON))
+	} else {
+		name := strings.TrimPrefix(policy.FullPath, "sys/policies/acl/")
+		fmt.Printf("Policy match:\n\tPolicy: %s\n\tLine: %d\n\tValue: %s\n\n", name, policy.Line, strings.TrimSpace(policy.Value))
+	}
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPolicySearch(t *testing.T) {
+	client, closer := testVaultServerWithTestcontainers(t)
+	defer closer()
+
+	policy := `path "secret/data/prod/*" {
+  capabilities = ["read"]
+}
+
+path "secret/data/dev/*" {
+  capabilities = ["read", "list"]
+}
+`
+	if err := client.Sys().PutPolicy("test-policy", policy); err != nil {
+		t.Fatalf("Failed to write policy: %v", err)
+	}
+
+	// Redirect stdout to a buffer
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	// Configure the vault client environment variables
+	if err := os.Setenv("VAULT_TOKEN", client.Token()); err != nil {
+		t.Fatalf("failed to set VAULT_TOKEN: %v", err)
+	}
+	if err := os.Setenv("VAULT_ADDR", client.Address()); err != nil {
+		t.Fatalf("failed to set VAULT_ADDR: %v", err)
+	}
+
+	matcher, err := newMatcher("substring", "secret/data/dev", false, defaultFuzzyOptions)
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+
+	matches := VaultPolicySearch("secret/data/dev", matcher, true, 30)
+
+	// Read from the buffer to get the stdout output
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+
+	actualOutput := strings.TrimSpace(buf.String())
+	expectedOutput := `{"search":"policy","path":"sys/policies/acl/test-policy","line":5,"value":"path \"secret/data/dev/*\" {"}`
+
+	if actualOutput != expectedOutput {
+		t.Errorf("Expected output '%s', but got '%s'", expectedOutput, actualOutput)
+	}
+	if matches != 1 {
+		t.Errorf("Expected 1 match, but got %d", matches)
+	}
+}
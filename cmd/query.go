@@ -0,0 +1,338 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A query combines comparisons against the key, value and path of every
+// key/value pair with AND, OR, NOT and parentheses, e.g.
+//
+//	key~"pass" AND path:"secret/prod/*" AND NOT value=""
+//
+// Comparison operators are:
+//
+//	=, !=           equality. Unquoted numbers and true/false compare numerically or as booleans
+//	<, <=, >, >=    numeric comparison
+//	~               regular expression match
+//	:               glob match, where * matches any characters and ? a single one
+
+// queryPair is the key/value pair a query is evaluated against
+type queryPair struct {
+	dirEntry string
+	fullPath string
+	keyPath  string
+	key      string
+	value    string
+}
+
+type queryNode interface {
+	eval(pair queryPair) bool
+}
+
+type queryAnd struct{ left, right queryNode }
+
+func (q queryAnd) eval(pair queryPair) bool { return q.left.eval(pair) && q.right.eval(pair) }
+
+type queryOr struct{ left, right queryNode }
+
+func (q queryOr) eval(pair queryPair) bool { return q.left.eval(pair) || q.right.eval(pair) }
+
+type queryNot struct{ node queryNode }
+
+func (q queryNot) eval(pair queryPair) bool { return !q.node.eval(pair) }
+
+type literalKind int
+
+const (
+	literalString literalKind = iota
+	literalNumber
+	literalBool
+)
+
+type queryComparison struct {
+	field   string
+	op      string
+	kind    literalKind
+	text    string
+	number  float64
+	boolean bool
+	pattern *regexp.Regexp
+}
+
+func (q *queryComparison) eval(pair queryPair) bool {
+	switch q.field {
+	case "key":
+		// Like the key search object, a key matches on either its full path or its name
+		return q.testAny(pair.keyPath, pair.key)
+	case "value":
+		return q.test(pair.value)
+	default:
+		// Like the path search object, a path matches on either the entry name or the full path. The path
+		// relative to its mount is tried too, so path:"prod/*" matches secret/prod/db.
+		_, relativePath, _ := strings.Cut(pair.fullPath, "/")
+		return q.testAny(pair.dirEntry, pair.fullPath, relativePath)
+	}
+}
+
+// testAny reports whether any of the forms of a field matches. A negated
+// comparison matches only if none of them is equal, so path!="secret/prod/db"
+// doesn't match secret/prod/db by its entry name.
+func (q *queryComparison) testAny(forms ...string) bool {
+	if q.op == "!=" {
+		return !slices.ContainsFunc(forms, q.equal)
+	}
+	return slices.ContainsFunc(forms, q.test)
+}
+
+func (q *queryComparison) test(s string) bool {
+	switch q.op {
+	case "~", ":":
+		return q.pattern.MatchString(s)
+	case "=":
+		return q.equal(s)
+	case "!=":
+		return !q.equal(s)
+	}
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return false
+	}
+	switch q.op {
+	case "<":
+		return n < q.number
+	case "<=":
+		return n <= q.number
+	case ">":
+		return n > q.number
+	default:
+		return n >= q.number
+	}
+}
+
+func (q *queryComparison) equal(s string) bool {
+	switch q.kind {
+	case literalNumber:
+		n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		return err == nil && n == q.number
+	case literalBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		return err == nil && b == q.boolean
+	default:
+		return s == q.text
+	}
+}
+
+type queryToken struct {
+	text   string
+	quoted bool
+	offset int
+}
+
+// queryOperators is ordered so that two character operators are tried first
+var queryOperators = []string{"!=", "<=", ">=", "=", "<", ">", "~", ":", "(", ")"}
+
+var queryComparisonOperators = map[string]struct{}{
+	"=": {}, "!=": {}, "<": {}, "<=": {}, ">": {}, ">=": {}, "~": {}, ":": {},
+}
+
+func tokenizeQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(query) && query[end] != '"' {
+				if query[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(query) {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			text, err := strconv.Unquote(query[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at offset %d: %w", i, err)
+			}
+			tokens = append(tokens, queryToken{text, true, i})
+			i = end + 1
+		default:
+			if op := queryOperatorAt(query, i); op != "" {
+				tokens = append(tokens, queryToken{op, false, i})
+				i += len(op)
+				continue
+			}
+			end := i
+			for end < len(query) && !strings.ContainsRune(" \t\n\r\"", rune(query[end])) && queryOperatorAt(query, end) == "" {
+				end++
+			}
+			tokens = append(tokens, queryToken{query[i:end], false, i})
+			i = end
+		}
+	}
+
+	return tokens, nil
+}
+
+func queryOperatorAt(query string, i int) string {
+	for _, op := range queryOperators {
+		if strings.HasPrefix(query[i:], op) {
+			return op
+		}
+	}
+	return ""
+}
+
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+// parseQuery parses a query so it can be evaluated against every key/value pair
+func parseQuery(query string) (queryNode, error) {
+	tokens, err := tokenizeQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("invalid query: query is empty")
+	}
+
+	p := &queryParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q at offset %d", p.tokens[p.pos].text, p.tokens[p.pos].offset)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	return node, nil
+}
+
+func (p *queryParser) peekKeyword(keyword string) bool {
+	if p.pos >= len(p.tokens) {
+		return false
+	}
+	t := p.tokens[p.pos]
+	return !t.quoted && strings.EqualFold(t.text, keyword)
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("OR") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = queryOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("AND") {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = queryAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseNot() (queryNode, error) {
+	if p.peekKeyword("NOT") {
+		p.pos++
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return queryNot{node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() (queryNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+
+	if p.peekKeyword("(") {
+		p.pos++
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peekKeyword(")") {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return node, nil
+	}
+
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (queryNode, error) {
+	if p.pos+2 >= len(p.tokens) {
+		return nil, fmt.Errorf("incomplete comparison at offset %d", p.tokens[p.pos].offset)
+	}
+	field, op, literal := p.tokens[p.pos], p.tokens[p.pos+1], p.tokens[p.pos+2]
+	p.pos += 3
+
+	name := strings.ToLower(field.text)
+	if field.quoted || (name != "key" && name != "value" && name != "path") {
+		return nil, fmt.Errorf("unknown field %q at offset %d. Fields are [key value path]", field.text, field.offset)
+	}
+	if _, ok := queryComparisonOperators[op.text]; op.quoted || !ok {
+		return nil, fmt.Errorf("expected operator after %s at offset %d", field.text, op.offset)
+	}
+	if !literal.quoted && queryOperatorAt(literal.text, 0) != "" {
+		return nil, fmt.Errorf("expected value after %s%s at offset %d", field.text, op.text, literal.offset)
+	}
+
+	c := &queryComparison{field: name, op: op.text, kind: literalString, text: literal.text}
+	if !literal.quoted {
+		if n, err := strconv.ParseFloat(literal.text, 64); err == nil {
+			c.kind, c.number = literalNumber, n
+		} else if literal.text == "true" || literal.text == "false" {
+			c.kind, c.boolean = literalBool, literal.text == "true"
+		}
+	}
+
+	var err error
+	switch c.op {
+	case "~":
+		c.pattern, err = regexp.Compile(c.text)
+	case ":":
+		// Like --match glob, * also matches newlines of multi-line values
+		c.pattern, err = regexp.Compile("(?s)" + globToRegexp(c.text))
+	case "<", "<=", ">", ">=":
+		if c.kind != literalNumber {
+			err = fmt.Errorf("%s needs a number, got %q", c.op, c.text)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid comparison at offset %d: %w", field.offset, err)
+	}
+
+	return c, nil
+}
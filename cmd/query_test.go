@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestQueryEval(t *testing.T) {
+	pair := queryPair{
+		dirEntry: "db",
+		fullPath: "secret/prod/db",
+		keyPath:  "database.db_password",
+		key:      "db_password",
+		value:    "5432",
+	}
+
+	tests := []struct {
+		query    string
+		expected bool
+	}{
+		{`key~"pass"`, true},
+		{`key~"^pass"`, false},
+		{`key="database.db_password"`, true},
+		{`key:"database.*"`, true},
+		{`key!="db_password"`, false},
+		{`key!="database.db_password"`, false},
+		{`key!="password"`, true},
+		{`NOT key!="db_password"`, true},
+		{`path:"secret/prod/*"`, true},
+		{`path:"db"`, true},
+		{`path:"prod/*"`, true},
+		{`path:"dev/*"`, false},
+		{`path!="secret/prod/db"`, false},
+		{`path!="db"`, false},
+		{`path!="prod/db"`, false},
+		{`path!="secret/dev/db"`, true},
+		{`value=5432`, true},
+		{`value=5432.0`, true},
+		{`value="5432.0"`, false},
+		{`value>5000 AND value<=5432`, true},
+		{`value>6000`, false},
+		{`key~"pass" AND path:"secret/prod/*" AND NOT value=""`, true},
+		{`key~"user" OR value=5432`, true},
+		{`NOT (key~"user" OR value=5432)`, false},
+		{`key="db_password" and not path:"*/dev/*"`, true},
+		{`value=true`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			node, err := parseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("Failed to parse query: %v", err)
+			}
+			if actual := node.eval(pair); actual != tt.expected {
+				t.Errorf("Expected %v, but got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestQueryEvalMultiline(t *testing.T) {
+	node, err := parseQuery(`value:"*BEGIN*"`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	if !node.eval(queryPair{value: "x\n-----BEGIN CERT\n"}) {
+		t.Error("Expected value:\"*BEGIN*\" to match a multi-line value")
+	}
+}
+
+func TestQueryEvalBool(t *testing.T) {
+	node, err := parseQuery(`value=true`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	if !node.eval(queryPair{value: "TRUE"}) {
+		t.Error("Expected value=true to match TRUE")
+	}
+
+	node, err = parseQuery(`value="true"`)
+	if err != nil {
+		t.Fatalf("Failed to parse query: %v", err)
+	}
+	if node.eval(queryPair{value: "TRUE"}) {
+		t.Error("Expected quoted \"true\" to compare as a string")
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`key`,
+		`key~`,
+		`name="foo"`,
+		`key=="foo"`,
+		`key~"("`,
+		`value>"abc"`,
+		`(key="foo"`,
+		`key="foo" value="bar"`,
+		`key="foo`,
+	}
+
+	for _, query := range tests {
+		if _, err := parseQuery(query); err == nil {
+			t.Errorf("Expected query '%s' to be rejected", query)
+		}
+	}
+}
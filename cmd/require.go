@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(requireKeysCmd)
+
+	requireKeysCmd.Flags().StringArrayVar(&requireRules, "require", nil, "Secrets whose path matches a glob "+
+		"must have these keys, e.g. 'secret/prod/*=owner,rotation_date'. Can be specified multiple times")
+	requireKeysCmd.Flags().StringVar(&requireRulesFile, "rules-file", "", "JSON file mapping path globs to "+
+		"lists of required keys, e.g. {\"secret/prod/*\": [\"owner\", \"rotation_date\"]}")
+	requireKeysCmd.Flags().BoolVar(&requireAllowEmpty, "allow-empty", false, "Accept required keys with "+
+		"empty values")
+}
+
+var (
+	requireAllowEmpty bool
+	requireRules      []string
+	requireRulesFile  string
+)
+
+var requireKeysCmd = &cobra.Command{
+	Use:   "require-keys [flags] [search-path]",
+	Short: "Report secrets missing required keys",
+	Long: `Report secrets missing required keys, e.g. every secret under prod/ must have owner and rotation_date
+
+Rules map globs of secret paths to the keys secrets matching them must have.
+Keys are full key paths, like database.password, and count as missing if their
+value is empty unless --allow-empty is set. If no search-path is given, all
+available KV stores are crawled.
+
+Exits with status 1 if any secret is missing a required key, so it can be used
+as a CI check`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		rules, err := loadKeyRequirements(requireRules, requireRulesFile)
+		if err == nil && len(rules) == 0 {
+			err = fmt.Errorf("no rules given, use --require or --rules-file")
+		}
+		return err
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var searchPath string
+		if len(args) == 1 {
+			searchPath = args[0]
+		}
+		compliant := VaultRequireKeys(searchPath, RequireKeysOptions{
+			AllowEmpty:    requireAllowEmpty,
+			CrawlingDelay: crawlingDelay,
+			JSONOutput:    jsonOutput,
+			KvVersion:     kvVersion,
+			Rules:         requireRules,
+			RulesFile:     requireRulesFile,
+			Timeout:       timeout,
+		})
+		if !compliant {
+			os.Exit(exitStatusNoMatch)
+		}
+	},
+	Example: "vault-kv-search require-keys --require 'secret/prod/*=owner,rotation_date' secret/",
+}
+
+// RequireKeysOptions holds the settings of a required keys check
+type RequireKeysOptions struct {
+	// AllowEmpty accepts required keys with empty values
+	AllowEmpty    bool
+	CrawlingDelay int
+	JSONOutput    bool
+	KvVersion     int
+	// Rules are path globs mapped to required keys, like secret/prod/*=owner,rotation_date
+	Rules []string
+	// RulesFile is a JSON file mapping path globs to lists of required keys
+	RulesFile string
+	Timeout   int
+}
+
+// keyRequirement lists the keys the secrets matching a path glob must have
+type keyRequirement struct {
+	glob    string
+	pattern *regexp.Regexp
+	keys    []string
+}
+
+// loadKeyRequirements parses rules like secret/prod/*=owner,rotation_date and the rules of rulesFile, if given
+func loadKeyRequirements(rules []string, rulesFile string) ([]keyRequirement, error) {
+	globs := map[string][]string{}
+	for _, rule := range rules {
+		glob, keys, ok := strings.Cut(rule, "=")
+		if !ok || strings.TrimSpace(glob) == "" || strings.TrimSpace(keys) == "" {
+			return nil, fmt.Errorf("invalid rule %q, expected <path glob>=<key>[,<key>...]", rule)
+		}
+		for _, key := range strings.Split(keys, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				globs[strings.TrimSpace(glob)] = append(globs[strings.TrimSpace(glob)], key)
+			}
+		}
+	}
+
+	if rulesFile != "" {
+		data, err := os.ReadFile(rulesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rules file: %w", err)
+		}
+		var fileRules map[string][]string
+		if err := json.Unmarshal(data, &fileRules); err != nil {
+			return nil, fmt.Errorf("failed to parse rules file %s: %w", rulesFile, err)
+		}
+		for glob, keys := range fileRules {
+			globs[glob] = append(globs[glob], keys...)
+		}
+	}
+
+	var requirements []keyRequirement
+	for glob, keys := range globs {
+		pattern, err := compilePathGlob(glob)
+		if err != nil {
+			return nil, err
+		}
+		requirements = append(requirements, keyRequirement{glob, pattern, keys})
+	}
+	sort.Slice(requirements, func(i, j int) bool { return requirements[i].glob < requirements[j].glob })
+	return requirements, nil
+}
+
+type missingKeys struct {
+	Search   string   `json:"search"`
+	FullPath string   `json:"path"`
+	Missing  []string `json:"missing"`
+}
+
+// keyChecker collects the secrets missing required keys
+type keyChecker struct {
+	allowEmpty   bool
+	requirements []keyRequirement
+
+	mu      sync.Mutex
+	checked int
+	results []missingKeys
+}
+
+// VaultRequireKeys reports the secrets below searchPath, or all KV stores if
+// it is empty, that miss required keys. It returns whether all secrets comply.
+func VaultRequireKeys(searchPath string, opts RequireKeysOptions) bool {
+	client := newVaultClient(opts.Timeout)
+
+	requirements, err := loadKeyRequirements(opts.Rules, opts.RulesFile)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(exitStatusError)
+	}
+	checker := &keyChecker{allowEmpty: opts.AllowEmpty, requirements: requirements}
+
+	vc := vaultClient{
+		crawlingDelay: opts.CrawlingDelay,
+		jsonOutput:    opts.JSONOutput,
+		logical:       client.Logical(),
+		sys:           client.Sys(),
+		visit:         checker.visit,
+		wg:            sync.WaitGroup{},
+	}
+
+	vc.crawl(vc.startPaths(searchPath, opts.KvVersion), fmt.Sprintf("Checking %d required keys rules",
+		len(requirements)))
+
+	results := checker.sorted()
+	for _, result := range results {
+		vc.showMissingKeys(result)
+	}
+	if !vc.jsonOutput {
+		fmt.Printf("%d of %d checked secrets are missing required keys\n", len(results), checker.checked)
+	}
+	return len(results) == 0
+}
+
+func (c *keyChecker) visit(dirEntry string, fullPath string, version int, data map[string]interface{}) {
+	var required []string
+	for _, requirement := range c.requirements {
+		if requirement.pattern.MatchString(fullPath) {
+			required = append(required, requirement.keys...)
+		}
+	}
+	if len(required) == 0 {
+		return
+	}
+
+	var present []string
+	walkValues(version, data, func(v secretValue) {
+		if c.allowEmpty || v.value != "" {
+			present = append(present, v.path)
+		}
+	})
+
+	var missing []string
+	for _, key := range required {
+		if !hasKeyPath(present, key) && !slices.Contains(missing, key) {
+			missing = append(missing, key)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checked++
+	if len(missing) > 0 {
+		c.results = append(c.results, missingKeys{"require-keys", fullPath, missing})
+	}
+}
+
+// hasKeyPath reports whether key or a value nested below it, like key.field or key[0], is present
+func hasKeyPath(present []string, key string) bool {
+	for _, path := range present {
+		if path == key || strings.HasPrefix(path, key+".") || strings.HasPrefix(path, key+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// sorted returns the secrets missing required keys ordered by path
+func (c *keyChecker) sorted() []missingKeys {
+	sort.Slice(c.results, func(i, j int) bool { return c.results[i].FullPath < c.results[j].FullPath })
+	return c.results
+}
+
+func (vc *vaultClient) showMissingKeys(result missingKeys) {
+	if vc.jsonOutput {
+		resultJSON, err := json.Marshal(result)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "can't marshal JSON: %s\n", err)
+			return
+		}
+		fmt.Println(string(resultJSON))
+	} else {
+		fmt.Printf("Missing keys:\n\tSecret: %s\n\tMissing: %s\n\n", result.FullPath, strings.Join(result.Missing, ", "))
+	}
+}
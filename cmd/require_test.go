@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestLoadKeyRequirements(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`{"secret/prod/*": ["owner"], "secret/db/*": ["host", "port"]}`), 0o600); err != nil {
+		t.Fatalf("Failed to write rules file: %v", err)
+	}
+
+	requirements, err := loadKeyRequirements([]string{"secret/prod/*=rotation_date, owner"}, path)
+	if err != nil {
+		t.Fatalf("Failed to load rules: %v", err)
+	}
+
+	var actual []string
+	for _, requirement := range requirements {
+		actual = append(actual, requirement.glob)
+		actual = append(actual, requirement.keys...)
+	}
+	expected := []string{"secret/db/*", "host", "port", "secret/prod/*", "rotation_date", "owner", "owner"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected rules %v, but got %v", expected, actual)
+	}
+
+	for _, rule := range []string{"secret/prod/*", "=owner", "secret/prod/*="} {
+		if _, err := loadKeyRequirements([]string{rule}, ""); err == nil {
+			t.Errorf("Expected rule '%s' to be rejected", rule)
+		}
+	}
+}
+
+func TestKeyChecker(t *testing.T) {
+	requirements, err := loadKeyRequirements([]string{
+		"kv/prod/*=owner,rotation_date",
+		"kv/prod/db=database.password,replicas",
+	}, "")
+	if err != nil {
+		t.Fatalf("Failed to load rules: %v", err)
+	}
+	checker := &keyChecker{requirements: requirements}
+
+	checker.visit("db", "kv/prod/db", 2, map[string]interface{}{
+		"data": map[string]interface{}{
+			"owner":    "team-a",
+			"database": map[string]interface{}{"password": "s3cret"},
+			"replicas": []interface{}{"r1"},
+		},
+		"metadata": map[string]interface{}{"rotation_date": "2024-01-01"},
+	})
+	checker.visit("app", "kv/prod/app", 1, map[string]interface{}{
+		"owner":         "",
+		"rotation_date": "2024-01-01",
+	})
+	checker.visit("app", "kv/dev/app", 1, map[string]interface{}{})
+	// Vault paths are case-sensitive
+	checker.visit("app", "kv/PROD/app", 1, map[string]interface{}{})
+
+	expected := []missingKeys{
+		{"require-keys", "kv/prod/app", []string{"owner"}},
+		{"require-keys", "kv/prod/db", []string{"rotation_date"}},
+	}
+	if actual := checker.sorted(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected results %v, but got %v", expected, actual)
+	}
+	if checker.checked != 2 {
+		t.Errorf("Expected 2 checked secrets, but got %d", checker.checked)
+	}
+}
+
+func TestRequireKeysSkipsDeletedSecrets(t *testing.T) {
+	client, closer := testVaultServerWithTestcontainers(t)
+	defer closer()
+
+	if err := client.Sys().Mount("test-kv2", &api.MountInput{Type: "kv", Options: map[string]string{"version": "2"}}); err != nil {
+		t.Fatalf("Failed to mount test-kv2: %v", err)
+	}
+
+	logical := client.Logical()
+	for _, secret := range []struct {
+		path string
+		data map[string]interface{}
+	}{
+		{"test-kv2/data/app", map[string]interface{}{"owner": "team-a"}},
+		{"test-kv2/data/gone", map[string]interface{}{"owner": "team-b"}},
+		{"test-kv2/data/gone", map[string]interface{}{"user": "app"}},
+	} {
+		if _, err := logical.Write(secret.path, map[string]interface{}{"data": secret.data}); err != nil {
+			t.Fatalf("Failed to write test data to %s: %v", secret.path, err)
+		}
+	}
+	// Soft-delete the latest version, leaving a tombstone that reads as data: null
+	if _, err := logical.Delete("test-kv2/data/gone"); err != nil {
+		t.Fatalf("Failed to delete test-kv2/data/gone: %v", err)
+	}
+
+	if err := os.Setenv("VAULT_TOKEN", client.Token()); err != nil {
+		t.Fatalf("failed to set VAULT_TOKEN: %v", err)
+	}
+	if err := os.Setenv("VAULT_ADDR", client.Address()); err != nil {
+		t.Fatalf("failed to set VAULT_ADDR: %v", err)
+	}
+
+	compliant := VaultRequireKeys("test-kv2/", RequireKeysOptions{
+		CrawlingDelay: 15,
+		JSONOutput:    true,
+		KvVersion:     2,
+		Rules:         []string{"test-kv2/*=owner"},
+		Timeout:       30,
+	})
+	if !compliant {
+		t.Error("Expected secrets whose latest version is deleted to be skipped")
+	}
+}
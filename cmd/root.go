@@ -18,6 +18,8 @@ limitations under the License.
 
 import (
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -42,6 +44,133 @@ func checkInputs(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	termSources := 0
+	for _, set := range []bool{query != "", termsFile != "", termStdin, termFile != "", hashSpec != "", detect,
+		expiringWithin != ""} {
+		if set {
+			termSources++
+		}
+	}
+	if termSources > 1 {
+		return errors.New("only one of --query, --terms-file, --term-stdin, --term-file, --hash, --detect and " +
+			"--expiring-within can be used")
+	}
+
+	if _, err := lookupDecoders(decode); err != nil {
+		return err
+	}
+	if maxDecodedSize <= 0 {
+		return errors.New("--max-decoded-size must be positive")
+	}
+
+	if lineNumbers || contextLines != 0 {
+		if contextLines < 0 {
+			return errors.New("--context can't be negative")
+		}
+		// These match whole values, or don't match values at all
+		for flag, set := range map[string]bool{"--query": query != "", "--hash": hashSpec != "",
+			"--expiring-within": expiringWithin != ""} {
+			if set {
+				return fmt.Errorf("--line-number and --context can't be combined with %s", flag)
+			}
+		}
+	}
+
+	if maxResults < 0 {
+		return errors.New("--max-results can't be negative")
+	}
+	if maxDuration < 0 {
+		return errors.New("--max-duration can't be negative")
+	}
+
+	if countMatches && filesWithMatches {
+		return errors.New("--count can't be combined with --files-with-matches")
+	}
+	if countMatches && invert {
+		return errors.New("--count can't be combined with --invert, which reports secrets without matches")
+	}
+
+	if detectRules != "" && !detect {
+		return errors.New("--detect-rules needs --detect")
+	}
+
+	switch {
+	case query != "":
+		if cmd.Flags().Changed("search") {
+			return errors.New("--search can't be combined with --query, use the key, value and path fields instead")
+		}
+		if _, err := parseQuery(query); err != nil {
+			return err
+		}
+	case termsFile != "":
+		if mode, _ := matchModeFromFlags(); mode != "substring" && mode != "regex" {
+			return fmt.Errorf("--terms-file can't be combined with --match %s", mode)
+		}
+		terms, err := loadTerms(termsFile)
+		if err != nil {
+			return err
+		}
+		if _, err := newTermsMatcher(terms, useRegex, ignoreCase); err != nil {
+			return err
+		}
+	case detect:
+		if cmd.Flags().Changed("search") {
+			return errors.New("--search can't be combined with --detect, only values are scanned")
+		}
+		if _, err := loadDetectionRules(detectRules); err != nil {
+			return err
+		}
+	case expiringWithin != "":
+		if cmd.Flags().Changed("search") {
+			return errors.New("--search can't be combined with --expiring-within, only values are scanned")
+		}
+		if _, err := parseDuration(expiringWithin); err != nil {
+			return fmt.Errorf("invalid --expiring-within: %w", err)
+		}
+	case hashSpec != "":
+		if cmd.Flags().Changed("search") {
+			return errors.New("--search can't be combined with --hash, only values are hashed")
+		}
+		if showSecrets {
+			return errors.New("--showsecrets can't be combined with --hash, values are never shown")
+		}
+		if hmacKeyFile != "" {
+			var err error
+			if hmacKey, err = os.ReadFile(hmacKeyFile); err != nil {
+				return fmt.Errorf("failed to read HMAC key: %w", err)
+			}
+		}
+		if _, err := newHashMatcher(hashSpec, hmacKey); err != nil {
+			return err
+		}
+	case termStdin || termFile != "":
+		var err error
+		if termStdin {
+			hiddenTerm, err = readTermStdin()
+		} else {
+			hiddenTerm, err = readTermFile(termFile)
+		}
+		if err != nil {
+			return err
+		}
+		// Don't return the matcher's error, as it would echo the term
+		if _, err := searchMatcher(hiddenTerm); err != nil {
+			mode, _ := matchModeFromFlags()
+			return fmt.Errorf("the search term isn't valid for --match %s", mode)
+		}
+	default:
+		if _, err := searchMatcher(args[len(args)-1]); err != nil {
+			return err
+		}
+	}
+
+	if termFromFlags() {
+		if len(args) == 0 {
+			cmd.Printf("!!Warning!! searching all KV stores, since no search-path was specified\n")
+		}
+		return nil
+	}
+
 	if len(args) == 1 {
 		cmd.Printf("!!Warning!! searching all KV stores, since only one positional argument was specified\n")
 	}
@@ -49,6 +178,42 @@ func checkInputs(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// termFromFlags reports whether the search term is given by a flag instead of a positional argument
+func termFromFlags() bool {
+	return query != "" || termsFile != "" || termStdin || termFile != "" || hashSpec != "" || detect ||
+		expiringWithin != ""
+}
+
+// matchModeFromFlags returns the match mode selected by --match or its --regex and --fuzzy shorthands
+func matchModeFromFlags() (string, error) {
+	if useRegex && fuzzyMatch {
+		return "", errors.New("--regex can't be combined with --fuzzy")
+	}
+
+	shorthand := ""
+	if useRegex {
+		shorthand = "regex"
+	} else if fuzzyMatch {
+		shorthand = "fuzzy"
+	}
+	if shorthand == "" {
+		return matchMode, nil
+	}
+	if matchMode != "substring" && matchMode != shorthand {
+		return "", fmt.Errorf("--%s can't be combined with --match %s", shorthand, matchMode)
+	}
+	return shorthand, nil
+}
+
+// searchMatcher compiles pattern according to the --match, --regex, --fuzzy and --ignore-case flags
+func searchMatcher(pattern string) (Matcher, error) {
+	mode, err := matchModeFromFlags()
+	if err != nil {
+		return nil, err
+	}
+	return newMatcher(mode, pattern, ignoreCase, fuzzyOptions)
+}
+
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
 	Use:   "vault-kv-search [flags] [search-path] substring",
@@ -56,42 +221,195 @@ var RootCmd = &cobra.Command{
 	Long: `Recursively search Hashicorp Vault for substring
 
 If only one positional argument is given, it is assumed you want to search all 
-available KV stores and the argument specified is the substring you want to search for`,
+available KV stores and the argument specified is the substring you want to search for.
+
+With --query, --terms-file, --term-stdin, --term-file, --hash, --detect or --expiring-within, the only positional
+argument is the optional search-path.
+
+Like grep, exits with status 0 if anything was found, 1 if not and 2 on errors`,
 
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		return checkInputs(cmd, args)
 	},
+	Args: func(cmd *cobra.Command, args []string) error {
+		// A query, terms file, hash, detection, expiry or term read from stdin or a file takes the place of the substring
+		if termFromFlags() {
+			return cobra.MaximumNArgs(1)(cmd, args)
+		}
+		return cobra.RangeArgs(1, 2)(cmd, args)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
-		VaultKvSearch(args, searchObjects, showSecrets, useRegex, crawlingDelay, kvVersion, jsonOutput, timeout)
+		mode, _ := matchModeFromFlags()
+		found := VaultKvSearch(args, SearchOptions{
+			Context:          contextLines,
+			Count:            countMatches,
+			CrawlingDelay:    crawlingDelay,
+			FilesWithMatches: filesWithMatches,
+			First:            firstMatch,
+			Decode:           decode,
+			DecodeEncoded:    decodeEncoded,
+			Detect:           detect,
+			DetectRules:      detectRules,
+			ExpiringWithin:   expiringWithin,
+			Fuzzy:            fuzzyOptions,
+			Hash:             hashSpec,
+			HMACKey:          hmacKey,
+			IgnoreCase:       ignoreCase,
+			Invert:           invert,
+			JSONOutput:       jsonOutput,
+			KvVersion:        kvVersion,
+			LineNumbers:      lineNumbers,
+			MatchMode:        mode,
+			MaxDecodedSize:   maxDecodedSize,
+			MaxDuration:      maxDuration,
+			MaxResults:       maxResults,
+			ParseEmbedded:    parseEmbeddedValues,
+			Query:            query,
+			Quiet:            quiet,
+			SearchObjects:    searchObjects,
+			ShowSecrets:      showSecrets,
+			Term:             hiddenTerm,
+			TermsFile:        termsFile,
+			Timeout:          timeout,
+			TransitKeys:      transitKeys,
+			TransitMount:     transitMount,
+			UseRegex:         useRegex,
+		})
+		if !found {
+			os.Exit(exitStatusNoMatch)
+		}
 	},
-	Args:    cobra.RangeArgs(1, 2),
-	Example: "vault-kv-search kv/ foo",
+	Example: `vault-kv-search kv/ foo
+vault-kv-search --query 'key~"pass" AND path:"kv/prod/*" AND NOT value=""' kv/`,
 }
 
+// Exit statuses, like grep's. Subcommands checking secrets exit with
+// exitStatusNoMatch if any secret fails the check.
+const (
+	exitStatusMatch   = 0
+	exitStatusNoMatch = 1
+	exitStatusError   = 2
+)
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	cobra.CheckErr(RootCmd.Execute())
+	if err := RootCmd.Execute(); err != nil {
+		os.Exit(exitStatusError)
+	}
 }
 
 var (
-	crawlingDelay int
-	jsonOutput    bool
-	kvVersion     int
-	searchObjects []string
-	showSecrets   bool
-	timeout       int
-	useRegex      bool
+	contextLines        int
+	countMatches        bool
+	crawlingDelay       int
+	decode              []string
+	decodeEncoded       bool
+	detect              bool
+	detectRules         string
+	expiringWithin      string
+	filesWithMatches    bool
+	firstMatch          bool
+	fuzzyMatch          bool
+	fuzzyOptions        FuzzyOptions
+	hashSpec            string
+	hiddenTerm          string
+	hmacKey             []byte
+	hmacKeyFile         string
+	ignoreCase          bool
+	invert              bool
+	jsonOutput          bool
+	kvVersion           int
+	lineNumbers         bool
+	matchMode           string
+	maxDecodedSize      int
+	maxDuration         time.Duration
+	maxResults          int
+	parseEmbeddedValues bool
+	query               string
+	quiet               bool
+	searchObjects       []string
+	showSecrets         bool
+	termFile            string
+	termStdin           bool
+	termsFile           string
+	timeout             int
+	transitKeys         map[string]string
+	transitMount        string
+	useRegex            bool
 )
 
 func init() {
-	RootCmd.Flags().IntVarP(&crawlingDelay, "delay", "d", 15, "Crawling delay in millisconds")
-	RootCmd.Flags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
-	RootCmd.Flags().IntVarP(&kvVersion, "kv-version", "k", 0, "KV version (1,2). Autodetect if not defined")
+	RootCmd.PersistentFlags().IntVarP(&crawlingDelay, "delay", "d", 15, "Crawling delay in millisconds")
+	RootCmd.PersistentFlags().IntVarP(&kvVersion, "kv-version", "k", 0, "KV version (1,2). Autodetect if not defined")
+	RootCmd.PersistentFlags().BoolVar(&fuzzyMatch, "fuzzy", false, "Match values within an edit distance of "+
+		"the substring. Shorthand for --match fuzzy")
+	RootCmd.PersistentFlags().IntVar(&fuzzyOptions.MaxDistance, "max-distance", defaultFuzzyOptions.MaxDistance,
+		"Maximum edit distance of a fuzzy match")
+	RootCmd.PersistentFlags().Float64Var(&fuzzyOptions.MinSimilarity, "min-similarity", 0, "Minimum similarity "+
+		"ratio between 0 and 1 of a fuzzy match. Replaces --max-distance if set")
+	RootCmd.PersistentFlags().IntVar(&fuzzyOptions.MaxLength, "fuzzy-max-length", defaultFuzzyOptions.MaxLength,
+		"Skip values longer than this many characters when fuzzy matching")
+	RootCmd.PersistentFlags().BoolVarP(&ignoreCase, "ignore-case", "i", false, "Match case-insensitively, "+
+		"using Unicode case folding and NFKC normalization")
+	RootCmd.PersistentFlags().BoolVarP(&jsonOutput, "json", "j", false, "Output as JSON")
+	RootCmd.PersistentFlags().StringVar(&matchMode, "match", "substring", fmt.Sprintf("How the substring is "+
+		"matched. Choices are %v", matchModes))
+	RootCmd.PersistentFlags().BoolVarP(&useRegex, "regex", "r", false, "Enable searching regex substring. Shorthand for --match regex")
+	RootCmd.PersistentFlags().IntVarP(&timeout, "timeout", "t", 30, "Vault client timeout in seconds")
+
+	RootCmd.Flags().IntVarP(&contextLines, "context", "C", 0, "Show this many lines around matches in "+
+		"multi-line values, masking their values unless --showsecrets is set. Implies --line-number")
+	RootCmd.Flags().BoolVarP(&countMatches, "count", "c", false, "Print the number of matches of every secret "+
+		"with matches instead of the matches")
+	RootCmd.Flags().StringSliceVar(&decode, "decode", nil, fmt.Sprintf("Decode structured values and search "+
+		"their fields as virtual keys, like tls.crt#x509.san. Choices are any of %v", decoderNames()))
+	RootCmd.Flags().BoolVar(&decodeEncoded, "decode-encoded", false, "Also search base64 and base64url "+
+		"encoded values after decoding them and decompressing gzip or zlib data. Matches show the decodings used")
+	RootCmd.Flags().BoolVar(&detect, "detect", false, "Search values for structured credentials like AWS "+
+		"keys, GitHub tokens, private keys, JWTs, Slack webhooks and Vault tokens instead of a substring, "+
+		"reporting the ID of the rule that fired")
+	RootCmd.Flags().StringVar(&detectRules, "detect-rules", "", "JSON file of detection rules extending the "+
+		"built-in ones for --detect. A rule with the ID of a built-in rule replaces it")
+	RootCmd.Flags().StringVar(&expiringWithin, "expiring-within", "", "Search values for PEM certificates "+
+		"and JWTs expiring within this duration, like 30d or 12h, instead of a substring. Expired ones are included")
+	RootCmd.Flags().BoolVarP(&filesWithMatches, "files-with-matches", "l", false, "Print only the paths of "+
+		"secrets with matches")
+	RootCmd.Flags().BoolVar(&firstMatch, "first", false, "Stop matching a secret at its first match")
+	RootCmd.Flags().StringVar(&hashSpec, "hash", "", "Search for values by hash instead of a substring, "+
+		"e.g. sha256:<hex>. Algorithms are md5, sha1, sha256, sha512 and their hmac- variants. Values are never shown")
+	RootCmd.Flags().StringVar(&hmacKeyFile, "hmac-key-file", "", "File holding the key for --hash hmac- digests")
+	RootCmd.Flags().BoolVar(&invert, "invert", false, "Report the secrets without any match instead of the "+
+		"matches, like grep -L")
+	RootCmd.Flags().BoolVarP(&lineNumbers, "line-number", "n", false, "Match multi-line values line by line, "+
+		"reporting the line and column of every match")
+	RootCmd.Flags().IntVar(&maxDecodedSize, "max-decoded-size", defaultMaxDecodedSize, "Skip values larger than "+
+		"this many bytes after decoding or decompressing them with --decode-encoded")
+	RootCmd.Flags().DurationVar(&maxDuration, "max-duration", 0, "Stop the whole crawl after this long, e.g. "+
+		"30s or 5m, unlike --timeout which bounds single requests. Exits with status 2 if nothing was found by then")
+	RootCmd.Flags().IntVar(&maxResults, "max-results", 0, "Stop the crawl once this many matches are reported, "+
+		"or secrets with --count, --files-with-matches or --invert")
+	RootCmd.Flags().BoolVar(&parseEmbeddedValues, "parse-embedded", false, "Parse JSON, YAML, INI and dotenv "+
+		"documents stored as values and search their pairs as virtual keys, like config#database.password")
+	RootCmd.Flags().StringVar(&query, "query", "", "Search with a query instead of a substring, e.g. "+
+		"'key~\"pass\" AND path:\"kv/prod/*\" AND NOT value=\"\"'. Fields are key, value and path, operators are "+
+		"= != < <= > >= ~ (regex) and : (glob), combined with AND, OR, NOT and parentheses")
+	RootCmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "Print no results and stop at the first one, "+
+		"only exiting with status 0 if anything was found, 1 if not and 2 on errors")
 	RootCmd.Flags().StringSliceVar(&searchObjects, "search", []string{"value"}, "Which Vault objects to "+
 		"search against. Choices are any and all of the following 'key,value,path'. Can be specified multiple times or "+
 		"once using format CSV. Defaults to 'value'")
 	RootCmd.Flags().BoolVarP(&showSecrets, "showsecrets", "s", false, "Show secrets values")
-	RootCmd.Flags().BoolVarP(&useRegex, "regex", "r", false, "Enable searching regex substring")
-	RootCmd.Flags().IntVarP(&timeout, "timeout", "t", 30, "Vault client timeout in seconds")
+	RootCmd.Flags().StringVar(&termFile, "term-file", "", "Read the substring from this file instead of a "+
+		"positional argument, keeping it out of shell history and ps output")
+	RootCmd.Flags().BoolVar(&termStdin, "term-stdin", false, "Read the substring from the first line of stdin "+
+		"instead of a positional argument, or prompt for it without echo if stdin is a terminal")
+	RootCmd.Flags().StringVar(&termsFile, "terms-file", "", "Search for every term of this file in a single "+
+		"crawl instead of a substring. One term per line, optionally preceded by a label and a tab. Terms "+
+		"wrapped in slashes are regexes")
+	RootCmd.Flags().StringVar(&transitMount, "transit-decrypt", "", "Decrypt transit ciphertext values "+
+		"(vault:v1:...) with the transit engine at this mount before matching")
+	RootCmd.Flags().StringToStringVar(&transitKeys, "transit-key", map[string]string{}, "Transit key name to "+
+		"decrypt with, per secret path prefix, e.g. 'secret/payments/=payments'. The longest matching prefix wins "+
+		"and '*' sets the fallback key")
 }
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// decodeSSH exposes the type and SHA256 fingerprint of SSH public keys in
+// authorized_keys format and of PEM or OpenSSH private keys, as ssh.kind
+// (public or private), ssh.type, ssh.fingerprint and ssh.comment. Encrypted
+// private keys only have a fingerprint if their format stores the public key
+// unencrypted, and are marked with ssh.encrypted. Keys after the first one are
+// numbered, like ssh[1].type.
+func decodeSSH(value string) []decodedField {
+	var fields []decodedField
+	keys := 0
+	add := func(kind string, key ssh.PublicKey, extra ...decodedField) {
+		prefix := "ssh"
+		if keys > 0 {
+			prefix = fmt.Sprintf("ssh[%d]", keys)
+		}
+		keys++
+		fields = append(fields, decodedField{prefix + ".kind", kind})
+		if key != nil {
+			fields = append(fields,
+				decodedField{prefix + ".type", key.Type()},
+				decodedField{prefix + ".fingerprint", ssh.FingerprintSHA256(key)},
+			)
+		}
+		for _, field := range extra {
+			fields = append(fields, decodedField{prefix + "." + field.name, field.value})
+		}
+	}
+
+	// Public keys, one per line like in authorized_keys files
+	rest := []byte(value)
+	if !strings.Contains(value, "ssh-") && !strings.Contains(value, "ecdsa-") {
+		rest = nil
+	}
+	for len(rest) > 0 {
+		key, comment, _, next, err := ssh.ParseAuthorizedKey(rest)
+		if err != nil {
+			break
+		}
+		var extra []decodedField
+		if comment != "" {
+			extra = append(extra, decodedField{"comment", comment})
+		}
+		add("public", key, extra...)
+		rest = next
+	}
+
+	// Private keys
+	if strings.Contains(value, "PRIVATE KEY-----") {
+		rest := []byte(value)
+		for {
+			var block *pem.Block
+			block, rest = pem.Decode(rest)
+			if block == nil {
+				break
+			}
+			if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+				continue
+			}
+
+			signer, err := ssh.ParsePrivateKey(pem.EncodeToMemory(block))
+			var missing *ssh.PassphraseMissingError
+			switch {
+			case err == nil:
+				add("private", signer.PublicKey())
+			case errors.As(err, &missing):
+				add("private", missing.PublicKey, decodedField{"encrypted", "true"})
+			}
+		}
+	}
+
+	return fields
+}
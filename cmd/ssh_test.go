@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestDecodeSSH(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		t.Fatalf("Failed to convert public key: %v", err)
+	}
+	fingerprint := ssh.FingerprintSHA256(sshPublic)
+
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublic))) + " deploy@example.com"
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		t.Fatalf("Failed to marshal private key: %v", err)
+	}
+	encryptedBlock, err := ssh.MarshalPrivateKeyWithPassphrase(private, "", []byte("passphrase"))
+	if err != nil {
+		t.Fatalf("Failed to marshal encrypted private key: %v", err)
+	}
+
+	tests := []struct {
+		value    string
+		expected []decodedField
+	}{
+		{authorizedKey, []decodedField{
+			{"ssh.kind", "public"},
+			{"ssh.type", "ssh-ed25519"},
+			{"ssh.fingerprint", fingerprint},
+			{"ssh.comment", "deploy@example.com"},
+		}},
+		{string(pem.EncodeToMemory(block)), []decodedField{
+			{"ssh.kind", "private"},
+			{"ssh.type", "ssh-ed25519"},
+			{"ssh.fingerprint", fingerprint},
+		}},
+		{string(pem.EncodeToMemory(encryptedBlock)), []decodedField{
+			{"ssh.kind", "private"},
+			{"ssh.type", "ssh-ed25519"},
+			{"ssh.fingerprint", fingerprint},
+			{"ssh.encrypted", "true"},
+		}},
+		{"# keys\n" + authorizedKey + "\n" + authorizedKey + "\n", []decodedField{
+			{"ssh.kind", "public"},
+			{"ssh.type", "ssh-ed25519"},
+			{"ssh.fingerprint", fingerprint},
+			{"ssh.comment", "deploy@example.com"},
+			{"ssh[1].kind", "public"},
+			{"ssh[1].type", "ssh-ed25519"},
+			{"ssh[1].fingerprint", fingerprint},
+			{"ssh[1].comment", "deploy@example.com"},
+		}},
+		{"ssh-rsa not-a-key", nil},
+		{"hunter2", nil},
+	}
+
+	for _, tt := range tests {
+		if actual := decodeSSH(tt.value); !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Expected fields %v for '%s', but got %v", tt.expected, tt.value, actual)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	RootCmd.AddCommand(auditStrengthCmd)
+
+	auditStrengthCmd.Flags().StringSliceVar(&strengthKeys, "keys", defaultStrengthKeys, "Only audit values of "+
+		"keys matching these case-insensitive globs")
+	auditStrengthCmd.Flags().IntVar(&strengthMaxScore, "max-score", maxStrengthScore, fmt.Sprintf("Only report "+
+		"values scoring at most this, from 0 (trivially guessable) to %d (strong)", maxStrengthScore))
+}
+
+var (
+	strengthKeys     []string
+	strengthMaxScore int
+)
+
+// defaultStrengthKeys are globs of key names that usually hold passwords
+var defaultStrengthKeys = []string{"*passw*", "*passphrase*", "*pwd*", "*secret*", "*token*", "*api_key*",
+	"*apikey*", "*credential*"}
+
+var auditStrengthCmd = &cobra.Command{
+	Use:   "audit-strength [flags] [search-path]",
+	Short: "Score the strength of password-like values",
+	Long: `Score the values of password-like keys, to find placeholders like "changeme" and weak passwords
+
+Every value is checked for its length, the character classes it uses, its
+estimated entropy and whether it's a common password or placeholder, and gets a
+score from 0 (trivially guessable) to 4 (strong). Only the score and the reasons
+for it are reported, values are never shown. If no search-path is given, all
+available KV stores are crawled`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if strengthMaxScore < 0 || strengthMaxScore > maxStrengthScore {
+			return fmt.Errorf("--max-score must be between 0 and %d", maxStrengthScore)
+		}
+		_, err := compileGlobs(strengthKeys)
+		return err
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var searchPath string
+		if len(args) == 1 {
+			searchPath = args[0]
+		}
+		VaultAuditStrength(searchPath, AuditStrengthOptions{
+			CrawlingDelay: crawlingDelay,
+			JSONOutput:    jsonOutput,
+			Keys:          strengthKeys,
+			KvVersion:     kvVersion,
+			MaxScore:      strengthMaxScore,
+			Timeout:       timeout,
+		})
+	},
+	Example: "vault-kv-search audit-strength --max-score 2 secret/",
+}
+
+// AuditStrengthOptions holds the settings of a password strength audit
+type AuditStrengthOptions struct {
+	CrawlingDelay int
+	JSONOutput    bool
+	// Keys are globs of the key names whose values are audited
+	Keys      []string
+	KvVersion int
+	// MaxScore only reports values scoring at most this
+	MaxScore int
+	Timeout  int
+}
+
+const maxStrengthScore = 4
+
+//go:embed common-passwords.txt
+var commonPasswordsFile string
+
+// commonPasswords is the lower case set of bundled common passwords and placeholders
+var commonPasswords = func() map[string]struct{} {
+	passwords := map[string]struct{}{}
+	for _, line := range strings.Split(commonPasswordsFile, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			passwords[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return passwords
+}()
+
+type strengthResult struct {
+	Search   string   `json:"search"`
+	FullPath string   `json:"path"`
+	Key      string   `json:"key"`
+	Score    int      `json:"score"`
+	Issues   []string `json:"issues,omitempty"`
+}
+
+// evaluateStrength scores value from 0 to maxStrengthScore and lists what weakens it
+func evaluateStrength(value string) (int, []string) {
+	if value == "" {
+		return 0, []string{"empty"}
+	}
+	if isCommonPassword(value) {
+		return 0, []string{"common password or placeholder"}
+	}
+
+	var issues []string
+	length := utf8.RuneCountInString(value)
+
+	var lower, upper, digit, symbol, other bool
+	for _, r := range value {
+		switch {
+		case r < unicode.MaxASCII && unicode.IsLower(r):
+			lower = true
+		case r < unicode.MaxASCII && unicode.IsUpper(r):
+			upper = true
+		case r < unicode.MaxASCII && unicode.IsDigit(r):
+			digit = true
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+	classes, pool := 0, 0
+	for _, class := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.used {
+			classes++
+			pool += class.size
+		}
+	}
+
+	// The entropy of a random string of the same length and character classes,
+	// reduced for values repeating few characters, like "abababababab"
+	bits := float64(length) * math.Log2(float64(pool))
+	distinct := map[rune]struct{}{}
+	for _, r := range value {
+		distinct[r] = struct{}{}
+	}
+	if len(distinct) < length/2 {
+		bits = min(bits, float64(length)*max(shannonEntropy(value), 1))
+	}
+
+	score := maxStrengthScore
+	switch {
+	case bits < 28:
+		score = 0
+	case bits < 40:
+		score = 1
+	case bits < 60:
+		score = 2
+	case bits < 80:
+		score = 3
+	}
+	if bits < 60 {
+		issues = append(issues, fmt.Sprintf("low entropy (%.0f bits)", bits))
+	}
+
+	switch {
+	case length < 8:
+		score = min(score, 1)
+		issues = append(issues, fmt.Sprintf("only %d characters", length))
+	case length < 12:
+		score = min(score, 2)
+		issues = append(issues, fmt.Sprintf("only %d characters", length))
+	}
+	if classes < 2 && length < 20 {
+		score = min(score, 2)
+		issues = append(issues, "a single character class")
+	}
+
+	return score, issues
+}
+
+// isCommonPassword reports whether value is in the common password list, also
+// after removing trailing digits and punctuation like in "Password123!"
+func isCommonPassword(value string) bool {
+	lowered := strings.ToLower(strings.TrimSpace(value))
+	if _, ok := commonPasswords[lowered]; ok {
+		return true
+	}
+	stripped := strings.TrimRightFunc(lowered, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	_, ok := commonPasswords[stripped]
+	return stripped != "" && ok
+}
+
+// strengthAuditor collects the scores of values under password-like keys
+type strengthAuditor struct {
+	keys     []*regexp.Regexp
+	maxScore int
+
+	mu      sync.Mutex
+	results []strengthResult
+}
+
+// VaultAuditStrength reports the strength of password-like values below searchPath, or all KV stores if it is empty
+func VaultAuditStrength(searchPath string, opts AuditStrengthOptions) {
+	client := newVaultClient(opts.Timeout)
+
+	keys, err := compileGlobs(opts.Keys)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(exitStatusError)
+	}
+	auditor := &strengthAuditor{keys: keys, maxScore: opts.MaxScore}
+
+	vc := vaultClient{
+		crawlingDelay: opts.CrawlingDelay,
+		jsonOutput:    opts.JSONOutput,
+		logical:       client.Logical(),
+		sys:           client.Sys(),
+		visit:         auditor.visit,
+		wg:            sync.WaitGroup{},
+	}
+
+	vc.crawl(vc.startPaths(searchPath, opts.KvVersion), "Auditing the strength of password-like values")
+
+	for _, result := range auditor.sorted() {
+		vc.showStrength(result)
+	}
+}
+
+func (a *strengthAuditor) visit(dirEntry string, fullPath string, version int, data map[string]interface{}) {
+	walkValues(version, data, func(v secretValue) {
+		if !matchAnyGlob(a.keys, v.key) {
+			return
+		}
+		score, issues := evaluateStrength(v.value)
+		if score > a.maxScore {
+			return
+		}
+
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.results = append(a.results, strengthResult{"strength", fullPath, v.path, score, issues})
+	})
+}
+
+// sorted returns the results weakest first
+func (a *strengthAuditor) sorted() []strengthResult {
+	sort.Slice(a.results, func(i, j int) bool {
+		if a.results[i].Score != a.results[j].Score {
+			return a.results[i].Score < a.results[j].Score
+		}
+		if a.results[i].FullPath != a.results[j].FullPath {
+			return a.results[i].FullPath < a.results[j].FullPath
+		}
+		return a.results[i].Key < a.results[j].Key
+	})
+	return a.results
+}
+
+func (vc *vaultClient) showStrength(result strengthResult) {
+	if vc.jsonOutput {
+		resultJSON, err := json.Marshal(result)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "can't marshal JSON: %s\n", err)
+			return
+		}
+		fmt.Println(string(resultJSON))
+	} else {
+		fmt.Printf("Strength %d/%d:\n\tSecret: %s\n\tKey: %s\n", result.Score, maxStrengthScore, result.FullPath,
+			result.Key)
+		if len(result.Issues) > 0 {
+			fmt.Printf("\tIssues: %s\n", strings.Join(result.Issues, ", "))
+		}
+		fmt.Println()
+	}
+}
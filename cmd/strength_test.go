@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEvaluateStrength(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int
+	}{
+		{"", 0},
+		{"changeme", 0},
+		{"CHANGEME", 0},
+		{"Password123!", 0},
+		{"summer2024", 0},
+		{"123456", 0},
+		{"abc", 0},
+		{"aaaaaaaaaaaaaaaa", 0},
+		{"hunter22x", 2},
+		{"Xk9#mP2$vL", 2},
+		{"correcthorsebatterystaple", 4},
+		{"7Gq!x2Lp#9Zr@4Wm", 4},
+	}
+
+	for _, tt := range tests {
+		if actual, issues := evaluateStrength(tt.value); actual != tt.expected {
+			t.Errorf("Expected score %d for '%s', but got %d %v", tt.expected, tt.value, actual, issues)
+		}
+	}
+}
+
+func TestStrengthAuditor(t *testing.T) {
+	keys, err := compileGlobs(defaultStrengthKeys)
+	if err != nil {
+		t.Fatalf("Failed to compile globs: %v", err)
+	}
+	auditor := &strengthAuditor{keys: keys, maxScore: 2}
+
+	auditor.visit("db", "kv/prod/db", 1, map[string]interface{}{
+		"username": "admin",
+		"password": "changeme",
+		"api_key":  "7Gq!x2Lp#9Zr@4Wm",
+	})
+	auditor.visit("cache", "kv2/dev/cache", 2, map[string]interface{}{
+		"data":     map[string]interface{}{"DB_PASSWORD": "Xk9#mP2$vL"},
+		"metadata": map[string]interface{}{"created_time": "2024-01-01"},
+	})
+
+	expected := []strengthResult{
+		{"strength", "kv/prod/db", "password", 0, []string{"common password or placeholder"}},
+		{"strength", "kv2/dev/cache", "DB_PASSWORD", 2, []string{"only 10 characters"}},
+	}
+	if actual := auditor.sorted(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected results %v, but got %v", expected, actual)
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// readTermFile reads the search term from path, dropping the trailing newline
+func readTermFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read term file: %w", err)
+	}
+
+	searchTerm := strings.TrimRight(string(data), "\r\n")
+	if searchTerm == "" {
+		return "", fmt.Errorf("term file %s is empty", path)
+	}
+	return searchTerm, nil
+}
+
+// readTermStdin reads the search term from the first line of stdin. If stdin
+// is a terminal, the user is prompted for it without echoing what they type.
+func readTermStdin() (string, error) {
+	var searchTerm string
+
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		_, _ = fmt.Fprint(os.Stderr, "Search term: ")
+		input, err := term.ReadPassword(fd)
+		_, _ = fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read search term: %w", err)
+		}
+		searchTerm = string(input)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("failed to read search term from stdin: %w", err)
+		}
+		searchTerm = strings.TrimRight(line, "\r\n")
+	}
+
+	if searchTerm == "" {
+		return "", errors.New("no search term given on stdin")
+	}
+	return searchTerm, nil
+}
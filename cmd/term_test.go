@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadTermFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "term")
+	if err := os.WriteFile(path, []byte("p@ss word \r\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write term file: %v", err)
+	}
+
+	actual, err := readTermFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read term file: %v", err)
+	}
+	if actual != "p@ss word " {
+		t.Errorf("Expected term 'p@ss word ', but got '%s'", actual)
+	}
+
+	if err := os.WriteFile(path, []byte("\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write term file: %v", err)
+	}
+	if _, err := readTermFile(path); err == nil {
+		t.Error("Expected empty term file to be rejected")
+	}
+}
+
+func TestReadTermStdin(t *testing.T) {
+	r, w, _ := os.Pipe()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	if _, err := w.WriteString("hunter2\nignored\n"); err != nil {
+		t.Fatalf("failed to write to stdin: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+
+	actual, err := readTermStdin()
+	if err != nil {
+		t.Fatalf("Failed to read term from stdin: %v", err)
+	}
+	if actual != "hunter2" {
+		t.Errorf("Expected term 'hunter2', but got '%s'", actual)
+	}
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// searchTerm is a single entry of a terms file
+type searchTerm struct {
+	text  string
+	label string
+	regex bool
+}
+
+// loadTerms reads a terms file. Every line holds a term, optionally preceded
+// by a label and a tab. Terms wrapped in slashes, like /^AKIA/, are regular
+// expressions. Blank lines and lines starting with # are ignored.
+// Terms without a label are labeled with their line number.
+func loadTerms(path string) ([]searchTerm, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open terms file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var terms []searchTerm
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		term := searchTerm{text: line, label: fmt.Sprintf("line %d", lineNumber)}
+		if label, text, ok := strings.Cut(line, "\t"); ok {
+			term.label, term.text = strings.TrimSpace(label), text
+		}
+		if len(term.text) > 2 && strings.HasPrefix(term.text, "/") && strings.HasSuffix(term.text, "/") {
+			term.text, term.regex = term.text[1:len(term.text)-1], true
+		}
+		if term.text == "" {
+			return nil, fmt.Errorf("%s:%d: empty term", path, lineNumber)
+		}
+
+		terms = append(terms, term)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read terms file: %w", err)
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("terms file %s doesn't contain any terms", path)
+	}
+
+	return terms, nil
+}
+
+// termsMatcher searches for many terms in a single pass. Literal terms are
+// found with an Aho-Corasick automaton, regex terms are matched one by one.
+type termsMatcher struct {
+	terms      []searchTerm
+	literals   *ahoCorasick
+	literalIDs []int
+	regexes    map[int]*regexp.Regexp
+	ignoreCase bool
+}
+
+// newTermsMatcher compiles terms. With useRegex every term is a regular expression.
+func newTermsMatcher(terms []searchTerm, useRegex bool, ignoreCase bool) (Matcher, error) {
+	m := &termsMatcher{terms: terms, regexes: map[int]*regexp.Regexp{}, ignoreCase: ignoreCase}
+
+	var literals []string
+	for i, term := range terms {
+		if !term.regex && !useRegex {
+			text := term.text
+			if ignoreCase {
+				text = foldString(text)
+			}
+			literals = append(literals, text)
+			m.literalIDs = append(m.literalIDs, i)
+			continue
+		}
+
+		pattern := term.text
+		if ignoreCase {
+			pattern = "(?i)" + norm.NFKC.String(pattern)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex term %s: %w", term.label, err)
+		}
+		m.regexes[i] = re
+	}
+	m.literals = newAhoCorasick(literals)
+
+	return m, nil
+}
+
+func (m *termsMatcher) Match(s string) []Hit {
+	found := make([]bool, len(m.terms))
+
+	literal := s
+	if m.ignoreCase {
+		literal = foldString(s)
+	}
+	for _, id := range m.literals.findAll(literal) {
+		found[m.literalIDs[id]] = true
+	}
+
+	if len(m.regexes) > 0 {
+		subject := s
+		if m.ignoreCase {
+			subject = norm.NFKC.String(s)
+		}
+		for i, re := range m.regexes {
+			if re.MatchString(subject) {
+				found[i] = true
+			}
+		}
+	}
+
+	var hits []Hit
+	for i, ok := range found {
+		if ok {
+			hits = append(hits, Hit{Term: m.terms[i].text, Label: m.terms[i].label})
+		}
+	}
+	return hits
+}
+
+// ahoCorasick finds all occurrences of a set of byte strings in a single scan
+type ahoCorasick struct {
+	next   []map[byte]int
+	fail   []int
+	output [][]int
+}
+
+func newAhoCorasick(patterns []string) *ahoCorasick {
+	ac := &ahoCorasick{next: []map[byte]int{{}}, fail: []int{0}, output: [][]int{nil}}
+
+	// Build the trie
+	for id, pattern := range patterns {
+		node := 0
+		for i := 0; i < len(pattern); i++ {
+			child, ok := ac.next[node][pattern[i]]
+			if !ok {
+				child = len(ac.next)
+				ac.next = append(ac.next, map[byte]int{})
+				ac.fail = append(ac.fail, 0)
+				ac.output = append(ac.output, nil)
+				ac.next[node][pattern[i]] = child
+			}
+			node = child
+		}
+		ac.output[node] = append(ac.output[node], id)
+	}
+
+	// Compute failure links breadth first, so that a node's failure link
+	// points to the longest proper suffix of it that is also in the trie
+	queue := make([]int, 0, len(ac.next))
+	for _, child := range ac.next[0] {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		for c, child := range ac.next[node] {
+			queue = append(queue, child)
+			fail := ac.fail[node]
+			for fail > 0 && !ac.has(fail, c) {
+				fail = ac.fail[fail]
+			}
+			if next, ok := ac.next[fail][c]; ok && next != child {
+				fail = next
+			}
+			ac.fail[child] = fail
+			ac.output[child] = append(ac.output[child], ac.output[fail]...)
+		}
+	}
+
+	return ac
+}
+
+func (ac *ahoCorasick) has(node int, c byte) bool {
+	_, ok := ac.next[node][c]
+	return ok
+}
+
+// findAll returns the IDs of the patterns occurring in s, each at most once
+func (ac *ahoCorasick) findAll(s string) []int {
+	var ids []int
+	seen := map[int]bool{}
+
+	node := 0
+	for i := 0; i < len(s); i++ {
+		for node > 0 && !ac.has(node, s[i]) {
+			node = ac.fail[node]
+		}
+		if next, ok := ac.next[node][s[i]]; ok {
+			node = next
+		}
+		for _, id := range ac.output[node] {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	return ids
+}
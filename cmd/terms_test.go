@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAhoCorasick(t *testing.T) {
+	patterns := []string{"he", "she", "his", "hers", "s", "hello world"}
+	ac := newAhoCorasick(patterns)
+
+	for _, input := range []string{"ushers", "this", "hello world", "h", "", "shhe", "xyz"} {
+		var expected []int
+		for id, pattern := range patterns {
+			if strings.Contains(input, pattern) {
+				expected = append(expected, id)
+			}
+		}
+
+		actual := ac.findAll(input)
+		slices.Sort(actual)
+		if !slices.Equal(actual, expected) {
+			t.Errorf("Expected patterns %v in '%s', but got %v", expected, input, actual)
+		}
+	}
+}
+
+func TestLoadTerms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "terms.txt")
+	content := "# leaked during incident 42\n" +
+		"hunter2\n" +
+		"\n" +
+		"aws key\t/^AKIA[0-9A-Z]{16}$/\n" +
+		"db\tp@ss\tword\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write terms file: %v", err)
+	}
+
+	terms, err := loadTerms(path)
+	if err != nil {
+		t.Fatalf("Failed to load terms: %v", err)
+	}
+
+	expected := []searchTerm{
+		{text: "hunter2", label: "line 2"},
+		{text: "^AKIA[0-9A-Z]{16}$", label: "aws key", regex: true},
+		{text: "p@ss\tword", label: "db"},
+	}
+	if !slices.Equal(terms, expected) {
+		t.Errorf("Expected terms %v, but got %v", expected, terms)
+	}
+}
+
+func TestTermsMatcher(t *testing.T) {
+	terms := []searchTerm{
+		{text: "hunter2", label: "first"},
+		{text: "Secret", label: "second"},
+		{text: "^AKIA", label: "aws", regex: true},
+	}
+
+	matcher, err := newTermsMatcher(terms, false, true)
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+
+	hits := matcher.Match("akia-hunter2-SECRET")
+	var labels []string
+	for _, hit := range hits {
+		labels = append(labels, hit.Label)
+	}
+	if !slices.Equal(labels, []string{"first", "second", "aws"}) {
+		t.Errorf("Expected all terms to hit, but got %v", labels)
+	}
+
+	if hits := matcher.Match("nothing here"); hits != nil {
+		t.Errorf("Expected no hits, but got %v", hits)
+	}
+
+	if _, err := newTermsMatcher([]searchTerm{{text: "foo(", label: "bad"}}, true, false); err == nil {
+		t.Error("Expected invalid regex term to be rejected with --regex")
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// transitBatchSize caps the number of ciphertexts sent in a single decrypt request
+const transitBatchSize = 100
+
+// transitCiphertext matches values produced by the transit secrets engine
+var transitCiphertext = regexp.MustCompile(`^vault:v\d+:[A-Za-z0-9+/]+=*$`)
+
+// transitPlaintext is a value decrypted by the transit engine, so matches against it can be reported as decrypted
+type transitPlaintext string
+
+// transitRef points at a ciphertext value inside a secret's data
+type transitRef struct {
+	// path is the full key path of the value, like clients[2].secret
+	path       string
+	ciphertext string
+	// set replaces the value in the secret's data
+	set func(value interface{})
+}
+
+// transitKey returns the transit key configured for the secret at fullPath, or
+// an empty string if there is none.
+func (vc *vaultClient) transitKey(fullPath string) string {
+	key, longest := vc.transitKeys["*"], -1
+	for prefix, name := range vc.transitKeys {
+		if prefix != "*" && strings.HasPrefix(fullPath, prefix) && len(prefix) > longest {
+			key, longest = name, len(prefix)
+		}
+	}
+	return key
+}
+
+// collectTransitCiphertexts appends a reference to every transit ciphertext
+// value in data, including nested maps and lists.
+func collectTransitCiphertexts(data map[string]interface{}, refs []transitRef) []transitRef {
+	for key, value := range data {
+		refs = collectTransitValue(key, value, func(value interface{}) { data[key] = value }, refs)
+	}
+	return refs
+}
+
+func collectTransitValue(path string, value interface{}, set func(value interface{}), refs []transitRef) []transitRef {
+	switch v := value.(type) {
+	case string:
+		if transitCiphertext.MatchString(v) {
+			refs = append(refs, transitRef{path, v, set})
+		}
+	case map[string]interface{}:
+		for key, child := range v {
+			refs = collectTransitValue(path+"."+key, child, func(value interface{}) { v[key] = value }, refs)
+		}
+	case []interface{}:
+		for i, item := range v {
+			refs = collectTransitValue(fmt.Sprintf("%s[%d]", path, i), item, func(value interface{}) { v[i] = value }, refs)
+		}
+	}
+	return refs
+}
+
+// decryptTransitValues replaces the transit ciphertext values found in data
+// with their plaintext. Values that can't be decrypted are left untouched.
+func (vc *vaultClient) decryptTransitValues(fullPath string, data map[string]interface{}) {
+	refs := collectTransitCiphertexts(data, nil)
+	if len(refs) == 0 {
+		return
+	}
+
+	key := vc.transitKey(fullPath)
+	if key == "" {
+		_, _ = fmt.Fprintf(os.Stderr, "!!Warning!! no transit key configured for %s. Skipping decryption.\n", fullPath)
+		return
+	}
+
+	for start := 0; start < len(refs); start += transitBatchSize {
+		end := min(start+transitBatchSize, len(refs))
+		err := vc.transitDecrypt(key, fullPath, refs[start:end])
+		// The crawl was stopped early
+		if vc.ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "!!Warning!! failed to decrypt %s with transit key %s: %s\n", fullPath, key, err)
+		}
+	}
+}
+
+// transitDecrypt decrypts the referenced ciphertexts in a single batch request
+// and stores the plaintext back in place.
+func (vc *vaultClient) transitDecrypt(key string, fullPath string, refs []transitRef) error {
+	batch := make([]interface{}, len(refs))
+	for i, ref := range refs {
+		batch[i] = map[string]interface{}{"ciphertext": ref.ciphertext}
+	}
+
+	decryptPath := fmt.Sprintf("%s/decrypt/%s", vc.transitMount, key)
+	secret, err := vc.logical.WriteWithContext(vc.ctx, decryptPath, map[string]interface{}{
+		"batch_input": batch,
+		// Report failed items in batch_results instead of failing the whole request
+		"partial_failure_response_code": http.StatusOK,
+	})
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return fmt.Errorf("empty response from %s", vc.transitMount)
+	}
+
+	results, _ := secret.Data["batch_results"].([]interface{})
+	if len(results) != len(refs) {
+		return fmt.Errorf("expected %d batch results, got %d", len(refs), len(results))
+	}
+
+	for i, result := range results {
+		item, _ := result.(map[string]interface{})
+		if msg, _ := item["error"].(string); msg != "" {
+			_, _ = fmt.Fprintf(os.Stderr, "!!Warning!! failed to decrypt key %s of %s: %s\n", refs[i].path, fullPath, msg)
+			continue
+		}
+
+		encoded, _ := item["plaintext"].(string)
+		plaintext, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "!!Warning!! can't decode plaintext of key %s of %s: %s\n", refs[i].path, fullPath, err)
+			continue
+		}
+		refs[i].set(transitPlaintext(plaintext))
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/api"
+)
+
+func TestTransitKey(t *testing.T) {
+	vc := vaultClient{transitKeys: map[string]string{
+		"*":                      "default",
+		"secret/payments/":       "payments",
+		"secret/payments/cards/": "cards",
+	}}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"secret/other/db", "default"},
+		{"secret/payments/db", "payments"},
+		{"secret/payments/cards/visa", "cards"},
+	}
+
+	for _, tt := range tests {
+		if actual := vc.transitKey(tt.path); actual != tt.expected {
+			t.Errorf("Expected transit key '%s' for %s, but got '%s'", tt.expected, tt.path, actual)
+		}
+	}
+
+	vc = vaultClient{transitKeys: map[string]string{"secret/payments/": "payments"}}
+	if actual := vc.transitKey("secret/other/db"); actual != "" {
+		t.Errorf("Expected no transit key without a fallback, but got '%s'", actual)
+	}
+}
+
+func TestCollectTransitCiphertexts(t *testing.T) {
+	data := map[string]interface{}{
+		"plain":  "vault:v1:not base64!",
+		"cipher": "vault:v1:8SDd3WHDOjf7mq69CyCqYjBXAiQQAVZRkFM13ok481zoCmHnSeDX9vyf7w==",
+		"nested": map[string]interface{}{
+			"cipher": "vault:v12:c2VjcmV0",
+			"number": "42",
+		},
+		"list": []interface{}{"plain", map[string]interface{}{"cipher": "vault:v1:c2VjcmV0"}, "vault:v2:c2VjcmV0"},
+	}
+
+	refs := collectTransitCiphertexts(data, nil)
+	var paths []string
+	for _, ref := range refs {
+		paths = append(paths, ref.path)
+		ref.set(transitPlaintext("decrypted"))
+	}
+	sort.Strings(paths)
+	expected := []string{"cipher", "list[1].cipher", "list[2]", "nested.cipher"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("Expected ciphertexts %v, but got %v", expected, paths)
+	}
+
+	list := data["list"].([]interface{})
+	if list[2] != transitPlaintext("decrypted") || list[1].(map[string]interface{})["cipher"] != transitPlaintext("decrypted") {
+		t.Errorf("Expected the ciphertexts in lists to be replaced, but got %v", list)
+	}
+	if data["plain"] != "vault:v1:not base64!" {
+		t.Errorf("Expected invalid ciphertexts to be left untouched, but got %v", data["plain"])
+	}
+}
+
+func TestTransitDecryptSearch(t *testing.T) {
+	client, closer := testVaultServerWithTestcontainers(t)
+	defer closer()
+
+	sysClient := client.Sys()
+	if err := sysClient.Mount("transit", &api.MountInput{Type: "transit"}); err != nil {
+		t.Fatalf("Failed to mount transit: %v", err)
+	}
+	if err := sysClient.Mount("test-kv1", &api.MountInput{Type: "kv", Options: map[string]string{"version": "1"}}); err != nil {
+		t.Fatalf("Failed to mount test-kv1: %v", err)
+	}
+
+	logical := client.Logical()
+	if _, err := logical.Write("transit/keys/app", nil); err != nil {
+		t.Fatalf("Failed to create transit key: %v", err)
+	}
+	encrypted, err := logical.Write("transit/encrypt/app", map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString([]byte("hidden-password")),
+	})
+	if err != nil {
+		t.Fatalf("Failed to encrypt test data: %v", err)
+	}
+
+	if _, err := logical.Write("test-kv1/app", map[string]interface{}{
+		"password": encrypted.Data["ciphertext"],
+		"username": "hidden-user",
+	}); err != nil {
+		t.Fatalf("Failed to write test data to KVv1: %v", err)
+	}
+
+	// Redirect stdout to a buffer
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	// Configure the vault client environment variables
+	if err := os.Setenv("VAULT_TOKEN", client.Token()); err != nil {
+		t.Fatalf("failed to set VAULT_TOKEN: %v", err)
+	}
+	if err := os.Setenv("VAULT_ADDR", client.Address()); err != nil {
+		t.Fatalf("failed to set VAULT_ADDR: %v", err)
+	}
+
+	VaultKvSearch([]string{"test-kv1/", "hidden-pass"}, SearchOptions{
+		CrawlingDelay: 15,
+		JSONOutput:    true,
+		KvVersion:     1,
+		SearchObjects: []string{"value"},
+		ShowSecrets:   true,
+		Timeout:       30,
+		TransitKeys:   map[string]string{"*": "app"},
+		TransitMount:  "transit",
+	})
+
+	// Read from the buffer to get the stdout output
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close writer: %v", err)
+	}
+	var buf bytes.Buffer
+	_, _ = buf.ReadFrom(r)
+
+	actualOutput := strings.TrimSpace(buf.String())
+	expectedOutput := `{"search":"value","path":"test-kv1/app","key":"password","value":"hidden-password","decrypted":true}`
+
+	if actualOutput != expectedOutput {
+		t.Errorf("Expected output '%s', but got '%s'", expectedOutput, actualOutput)
+	}
+}
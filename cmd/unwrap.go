@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// defaultMaxDecodedSize is the default limit in bytes of a decoded or decompressed value
+	defaultMaxDecodedSize = 1 << 20
+	// maxDecodeSteps limits how many decodings are chained, like base64+gzip+base64
+	maxDecodeSteps = 4
+	// minEncodedLength skips short values, which are often words that happen to be valid base64
+	minEncodedLength = 16
+)
+
+var encodedCandidate = regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`)
+
+// unwrapValue decodes a base64 or base64url encoded value and decompresses
+// the result while it is gzip or zlib compressed. It returns the decodings
+// applied, like [base64 gzip], and the decoded text, or ok false if value
+// isn't encoded. Decoded values larger than maxSize bytes are an error, so
+// that decompression bombs are skipped.
+func unwrapValue(value string, maxSize int) (steps []string, text string, ok bool, err error) {
+	compact := strings.Join(strings.Fields(value), "")
+	if len(compact) < minEncodedLength || !encodedCandidate.MatchString(compact) {
+		return nil, "", false, nil
+	}
+
+	data, step, ok := decodeBase64(compact)
+	if !ok {
+		return nil, "", false, nil
+	}
+	if len(data) > maxSize {
+		return nil, "", false, fmt.Errorf("decoded value is larger than %d bytes", maxSize)
+	}
+	steps = []string{step}
+
+	for len(steps) < maxDecodeSteps {
+		var reader io.ReadCloser
+		switch {
+		case len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b:
+			reader, err = gzip.NewReader(bytes.NewReader(data))
+			step = "gzip"
+		case isZlibHeader(data):
+			reader, err = zlib.NewReader(bytes.NewReader(data))
+			step = "zlib"
+		}
+		if reader == nil && err == nil {
+			break
+		}
+		if err != nil {
+			return nil, "", false, fmt.Errorf("invalid %s data: %w", step, err)
+		}
+
+		data, err = io.ReadAll(io.LimitReader(reader, int64(maxSize)+1))
+		_ = reader.Close()
+		if err != nil {
+			return nil, "", false, fmt.Errorf("invalid %s data: %w", step, err)
+		}
+		if len(data) > maxSize {
+			return nil, "", false, fmt.Errorf("decompressed value is larger than %d bytes", maxSize)
+		}
+		steps = append(steps, step)
+	}
+
+	text, ok = printableText(data)
+	if !ok {
+		return nil, "", false, nil
+	}
+	return steps, text, true, nil
+}
+
+// decodeBase64 decodes s with the standard or URL alphabet, with or without padding
+func decodeBase64(s string) ([]byte, string, bool) {
+	encodings := []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding}
+	name := "base64"
+	if strings.ContainsAny(s, "-_") {
+		encodings = []*base64.Encoding{base64.URLEncoding, base64.RawURLEncoding}
+		name = "base64url"
+	}
+	for _, encoding := range encodings {
+		if data, err := encoding.DecodeString(s); err == nil {
+			return data, name, true
+		}
+	}
+	return nil, "", false
+}
+
+// isZlibHeader reports whether data starts with a zlib header using deflate
+func isZlibHeader(data []byte) bool {
+	return len(data) > 2 && data[0]&0x0f == 8 && data[0]>>4 <= 7 && (uint16(data[0])<<8|uint16(data[1]))%31 == 0
+}
+
+// printableText returns data as text if it is mostly printable UTF-8. Of
+// binary data, like keystores, it returns the runs of at least 6 printable
+// ASCII characters, one per line, like the strings command.
+func printableText(data []byte) (string, bool) {
+	if utf8.Valid(data) {
+		printable, total := 0, 0
+		for _, r := range string(data) {
+			total++
+			if unicode.IsPrint(r) || unicode.IsSpace(r) {
+				printable++
+			}
+		}
+		if total > 0 && printable*10 >= total*9 {
+			return string(data), true
+		}
+	}
+
+	var runs []string
+	start := -1
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && data[i] >= 0x20 && data[i] < 0x7f {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && i-start >= 6 {
+			runs = append(runs, string(data[start:i]))
+		}
+		start = -1
+	}
+	if len(runs) == 0 {
+		return "", false
+	}
+	return strings.Join(runs, "\n"), true
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Failed to gzip: %v", err)
+	}
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+func zlibBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+func TestUnwrapValue(t *testing.T) {
+	kubeconfig := "apiVersion: v1\nkind: Config\nusers:\n- name: admin\n  user:\n    token: s3cret-token\n"
+	keystore := append([]byte{0xfe, 0xed, 0xfe, 0xed, 0x00, 0x02}, []byte("alias-payments\x00\x01\x02\xff")...)
+
+	tests := []struct {
+		name          string
+		value         string
+		expectedSteps []string
+		expectedText  string
+	}{
+		{"base64", base64.StdEncoding.EncodeToString([]byte(kubeconfig)), []string{"base64"}, kubeconfig},
+		{"wrapped base64", wrapLines(base64.StdEncoding.EncodeToString([]byte(kubeconfig)), 20), []string{"base64"},
+			kubeconfig},
+		{"base64url", base64.RawURLEncoding.EncodeToString([]byte("subjects?_d=1>>>>~~~~")), []string{"base64url"},
+			"subjects?_d=1>>>>~~~~"},
+		{"gzip", base64.StdEncoding.EncodeToString(gzipBytes(t, []byte(kubeconfig))), []string{"base64", "gzip"},
+			kubeconfig},
+		{"zlib", base64.StdEncoding.EncodeToString(zlibBytes(t, []byte(kubeconfig))), []string{"base64", "zlib"},
+			kubeconfig},
+		{"gzip in gzip", base64.StdEncoding.EncodeToString(gzipBytes(t, gzipBytes(t, []byte(kubeconfig)))),
+			[]string{"base64", "gzip", "gzip"}, kubeconfig},
+		{"binary", base64.StdEncoding.EncodeToString(keystore), []string{"base64"}, "alias-payments"},
+		{"short", "dGVzdA==", nil, ""},
+		{"not base64", "this is not base64 at all!", nil, ""},
+		{"plain word", "passwordpasswordpassword", nil, ""},
+	}
+
+	for _, tt := range tests {
+		steps, text, ok, err := unwrapValue(tt.value, defaultMaxDecodedSize)
+		if err != nil {
+			t.Errorf("Expected %s to decode, but got %v", tt.name, err)
+			continue
+		}
+		if ok != (tt.expectedSteps != nil) || !reflect.DeepEqual(steps, tt.expectedSteps) || text != tt.expectedText {
+			t.Errorf("Expected %s to decode with %v to '%s', but got %v '%s'", tt.name, tt.expectedSteps,
+				tt.expectedText, steps, text)
+		}
+	}
+}
+
+func TestUnwrapValueSizeLimit(t *testing.T) {
+	bomb := base64.StdEncoding.EncodeToString(gzipBytes(t, bytes.Repeat([]byte("a"), 1<<16)))
+	if _, _, _, err := unwrapValue(bomb, 1<<10); err == nil {
+		t.Error("Expected a decompressed value over the limit to be rejected")
+	}
+	if _, text, ok, err := unwrapValue(bomb, 1<<16); err != nil || !ok || len(text) != 1<<16 {
+		t.Errorf("Expected a decompressed value at the limit to be decoded, but got %d bytes (%v)", len(text), err)
+	}
+}
+
+func wrapLines(s string, width int) string {
+	var lines []string
+	for len(s) > width {
+		lines = append(lines, s[:width])
+		s = s[width:]
+	}
+	return strings.Join(append(lines, s), "\n")
+}
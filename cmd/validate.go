@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"github.com/spf13/cobra"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+func init() {
+	RootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringArrayVar(&validateSchemas, "schema", nil, "Secrets whose path matches a glob "+
+		"must validate against a JSON Schema file, e.g. 'secret/*/database=schemas/database.json'. Can be "+
+		"specified multiple times")
+}
+
+var validateSchemas []string
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [flags] [search-path]",
+	Short: "Validate secrets against JSON Schemas",
+	Long: `Validate secrets against JSON Schemas, e.g. database secrets must have host, port and password
+
+Rules map globs of secret paths to JSON Schema files, up to draft 2020-12, that
+the data of secrets matching them must validate against. Every violation is
+reported with the secret path and the JSON pointer of the invalid value. If no
+search-path is given, all available KV stores are crawled.
+
+Exits with status 1 if any secret is invalid, so it can be used as a CI check`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		rules, err := loadSchemaRules(validateSchemas)
+		if err == nil && len(rules) == 0 {
+			err = fmt.Errorf("no schemas given, use --schema")
+		}
+		return err
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var searchPath string
+		if len(args) == 1 {
+			searchPath = args[0]
+		}
+		valid := VaultValidate(searchPath, ValidateOptions{
+			CrawlingDelay: crawlingDelay,
+			JSONOutput:    jsonOutput,
+			KvVersion:     kvVersion,
+			Schemas:       validateSchemas,
+			Timeout:       timeout,
+		})
+		if !valid {
+			os.Exit(exitStatusNoMatch)
+		}
+	},
+	Example: "vault-kv-search validate --schema 'secret/*/database=schemas/database.json' secret/",
+}
+
+// ValidateOptions holds the settings of a JSON Schema validation
+type ValidateOptions struct {
+	CrawlingDelay int
+	JSONOutput    bool
+	KvVersion     int
+	// Schemas are path globs mapped to JSON Schema files, like secret/*/database=schemas/database.json
+	Schemas []string
+	Timeout int
+}
+
+// schemaRule is a JSON Schema the secrets matching a path glob must validate against
+type schemaRule struct {
+	glob    string
+	pattern *regexp.Regexp
+	file    string
+	schema  *jsonschema.Schema
+}
+
+// loadSchemaRules parses rules like secret/*/database=schemas/database.json and compiles their schemas
+func loadSchemaRules(rules []string) ([]schemaRule, error) {
+	compiler := jsonschema.NewCompiler()
+	var schemaRules []schemaRule
+	for _, rule := range rules {
+		glob, file, ok := strings.Cut(rule, "=")
+		glob, file = strings.TrimSpace(glob), strings.TrimSpace(file)
+		if !ok || glob == "" || file == "" {
+			return nil, fmt.Errorf("invalid schema rule %q, expected <path glob>=<schema file>", rule)
+		}
+
+		pattern, err := compilePathGlob(glob)
+		if err != nil {
+			return nil, err
+		}
+		schema, err := compiler.Compile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load schema %s: %w", file, err)
+		}
+		schemaRules = append(schemaRules, schemaRule{glob, pattern, file, schema})
+	}
+	return schemaRules, nil
+}
+
+type schemaViolation struct {
+	Search   string `json:"search"`
+	FullPath string `json:"path"`
+	Pointer  string `json:"pointer"`
+	Message  string `json:"message"`
+	Schema   string `json:"schema"`
+}
+
+// schemaValidator collects the violations of the secrets matching a schema rule
+type schemaValidator struct {
+	rules []schemaRule
+
+	mu         sync.Mutex
+	checked    int
+	invalid    map[string]bool
+	violations []schemaViolation
+}
+
+// VaultValidate validates the secrets below searchPath, or all KV stores if
+// it is empty, against the JSON Schemas of the rules matching their path. It
+// returns whether all secrets are valid.
+func VaultValidate(searchPath string, opts ValidateOptions) bool {
+	client := newVaultClient(opts.Timeout)
+
+	rules, err := loadSchemaRules(opts.Schemas)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(exitStatusError)
+	}
+	validator := &schemaValidator{rules: rules, invalid: map[string]bool{}}
+
+	vc := vaultClient{
+		crawlingDelay: opts.CrawlingDelay,
+		jsonOutput:    opts.JSONOutput,
+		logical:       client.Logical(),
+		sys:           client.Sys(),
+		visit:         validator.visit,
+		wg:            sync.WaitGroup{},
+	}
+
+	vc.crawl(vc.startPaths(searchPath, opts.KvVersion), fmt.Sprintf("Validating against %d schemas", len(rules)))
+
+	for _, violation := range validator.sorted() {
+		vc.showViolation(violation)
+	}
+	if !vc.jsonOutput {
+		fmt.Printf("%d of %d checked secrets are invalid\n", len(validator.invalid), validator.checked)
+	}
+	return len(validator.invalid) == 0
+}
+
+func (v *schemaValidator) visit(dirEntry string, fullPath string, version int, data map[string]interface{}) {
+	if version > 1 {
+		data, _ = data["data"].(map[string]interface{})
+	}
+	// Deleted KV v2 secrets have no data to validate, which isn't an empty object
+	if data == nil {
+		return
+	}
+
+	var violations []schemaViolation
+	matched := false
+	for _, rule := range v.rules {
+		if !rule.pattern.MatchString(fullPath) {
+			continue
+		}
+		matched = true
+
+		err := rule.schema.Validate(data)
+		var validationErr *jsonschema.ValidationError
+		switch {
+		case err == nil:
+		case errors.As(err, &validationErr):
+			for _, cause := range leafCauses(validationErr) {
+				violations = append(violations, schemaViolation{"validate", fullPath,
+					instancePointer(cause.InstanceLocation), violationMessage(cause.ErrorKind), rule.file})
+			}
+		default:
+			violations = append(violations, schemaViolation{"validate", fullPath, "", err.Error(), rule.file})
+		}
+	}
+	if !matched {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.checked++
+	if len(violations) > 0 {
+		v.invalid[fullPath] = true
+		v.violations = append(v.violations, violations...)
+	}
+}
+
+var messagePrinter = message.NewPrinter(language.English)
+
+// instancePointer returns the JSON pointer of a location in a validated secret, like /database/port
+func instancePointer(location []string) string {
+	var pointer strings.Builder
+	for _, token := range location {
+		pointer.WriteString("/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return pointer.String()
+}
+
+// violationMessage describes a validation error without the secret value it is about
+func violationMessage(errorKind jsonschema.ErrorKind) string {
+	switch k := errorKind.(type) {
+	case *kind.Pattern:
+		return fmt.Sprintf("value does not match pattern '%s'", k.Want)
+	case *kind.Format:
+		return fmt.Sprintf("value is not valid %s", k.Want)
+	}
+	return errorKind.LocalizedString(messagePrinter)
+}
+
+// leafCauses returns the innermost errors of a validation error, which name the invalid values
+func leafCauses(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var causes []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		causes = append(causes, leafCauses(cause)...)
+	}
+	return causes
+}
+
+// sorted returns the violations ordered by secret path and JSON pointer
+func (v *schemaValidator) sorted() []schemaViolation {
+	sort.SliceStable(v.violations, func(i, j int) bool {
+		if v.violations[i].FullPath != v.violations[j].FullPath {
+			return v.violations[i].FullPath < v.violations[j].FullPath
+		}
+		return v.violations[i].Pointer < v.violations[j].Pointer
+	})
+	return v.violations
+}
+
+func (vc *vaultClient) showViolation(violation schemaViolation) {
+	if vc.jsonOutput {
+		violationJSON, err := json.Marshal(violation)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "can't marshal JSON: %s\n", err)
+			return
+		}
+		fmt.Println(string(violationJSON))
+	} else {
+		pointer := violation.Pointer
+		if pointer == "" {
+			pointer = "/"
+		}
+		fmt.Printf("Schema violation:\n\tSecret: %s\n\tPointer: %s\n\tMessage: %s\n\tSchema: %s\n\n",
+			violation.FullPath, pointer, violation.Message, violation.Schema)
+	}
+}
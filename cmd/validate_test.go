@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadSchemaRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "database.json")
+	if err := os.WriteFile(path, []byte(`{"type": "object"}`), 0o600); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+
+	rules, err := loadSchemaRules([]string{"secret/*/database=" + path})
+	if err != nil {
+		t.Fatalf("Failed to load rules: %v", err)
+	}
+	if len(rules) != 1 || rules[0].glob != "secret/*/database" || rules[0].file != path {
+		t.Errorf("Unexpected rules %v", rules)
+	}
+
+	for _, rule := range []string{"secret/*/database", "=" + path, "secret/*/database=" + path + ".missing"} {
+		if _, err := loadSchemaRules([]string{rule}); err == nil {
+			t.Errorf("Expected rule '%s' to be rejected", rule)
+		}
+	}
+}
+
+func TestSchemaValidator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "database.json")
+	schema := `{
+		"type": "object",
+		"required": ["host", "port", "password"],
+		"properties": {
+			"host": {"type": "string"},
+			"port": {"type": "string", "pattern": "^[0-9]+$"},
+			"password": {"type": "string", "minLength": 8}
+		}
+	}`
+	if err := os.WriteFile(path, []byte(schema), 0o600); err != nil {
+		t.Fatalf("Failed to write schema: %v", err)
+	}
+	rules, err := loadSchemaRules([]string{"kv/*/database=" + path})
+	if err != nil {
+		t.Fatalf("Failed to load rules: %v", err)
+	}
+	validator := &schemaValidator{rules: rules, invalid: map[string]bool{}}
+
+	validator.visit("database", "kv/prod/database", 2, map[string]interface{}{
+		"data": map[string]interface{}{"host": "db", "port": "5432", "password": "correct-horse"},
+	})
+	validator.visit("database", "kv/dev/database", 2, map[string]interface{}{
+		"data": map[string]interface{}{"host": "db", "port": "five", "password": "short"},
+	})
+	validator.visit("database", "kv/test/database", 1, map[string]interface{}{
+		"host": "db", "port": json.Number("5432"),
+	})
+	validator.visit("app", "kv/prod/app", 1, map[string]interface{}{})
+	// Vault paths are case-sensitive
+	validator.visit("database", "kv/prod/DATABASE", 1, map[string]interface{}{})
+	validator.visit("database", "kv/stage/database", 2, map[string]interface{}{
+		"data": nil, "metadata": map[string]interface{}{"deletion_time": "2024-01-01T00:00:00Z"},
+	})
+
+	var actual []string
+	for _, violation := range validator.sorted() {
+		actual = append(actual, violation.FullPath+" "+violation.Pointer)
+		if strings.Contains(violation.Message, "five") || strings.Contains(violation.Message, "short") {
+			t.Errorf("Expected message '%s' not to show the secret value", violation.Message)
+		}
+	}
+	expected := []string{"kv/dev/database /password", "kv/dev/database /port", "kv/test/database ",
+		"kv/test/database /port"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected violations %v, but got %v", expected, actual)
+	}
+	if validator.checked != 3 || len(validator.invalid) != 2 {
+		t.Errorf("Expected 2 of 3 secrets to be invalid, but got %d of %d", len(validator.invalid), validator.checked)
+	}
+}
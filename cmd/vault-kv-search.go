@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,15 +20,107 @@ import (
 )
 
 type vaultClient struct {
-	crawlingDelay int
-	jsonOutput    bool
-	logical       *vault.Logical
-	searchObjects []string
-	searchString  string
-	showSecrets   bool
-	sys           *vault.Sys
-	useRegex      bool
-	wg            sync.WaitGroup
+	cancel           context.CancelFunc
+	context          int
+	count            bool
+	crawlingDelay    int
+	ctx              context.Context
+	decodeEncoded    bool
+	decoders         []valueDecoder
+	filesWithMatches bool
+	first            bool
+	invert           bool
+	maxDecodedSize   int
+	jsonOutput       bool
+	lineNumbers      bool
+	logical          *vault.Logical
+	matcher          Matcher
+	maxResults       int
+	metadataOnly     bool
+	parseEmbedded    bool
+	query            queryNode
+	quiet            bool
+	searchObjects    []string
+	searchString     string
+	showSecrets      bool
+	sys              *vault.Sys
+	transitKeys      map[string]string
+	transitMount     string
+	visit            secretVisitor
+	wg               sync.WaitGroup
+
+	mu sync.Mutex
+	// results counts the reported matches, or secrets with --invert, --count and --files-with-matches
+	results int
+}
+
+// secretVisitor is called with every secret read while crawling. It is called
+// concurrently from the crawling goroutines. With metadataOnly, it is called
+// with the metadata of KV v2 secrets instead of their data.
+type secretVisitor func(dirEntry string, fullPath string, version int, data map[string]interface{})
+
+// SearchOptions holds the settings of a KV search
+type SearchOptions struct {
+	// Context shows this many lines around line matches, implying LineNumbers
+	Context int
+	// Count reports the number of matches of every secret with matches instead of the matches
+	Count         bool
+	CrawlingDelay int
+	// Decode names the decoders exposing fields of structured values, like certificates, as virtual keys
+	Decode []string
+	// DecodeEncoded also searches base64 encoded values after decoding and decompressing them
+	DecodeEncoded bool
+	// Detect replaces the search string with the built-in credential detection rules
+	Detect bool
+	// DetectRules is a JSON file of detection rules extending the built-in ones
+	DetectRules string
+	// FilesWithMatches reports only the paths of secrets with matches, like grep -l
+	FilesWithMatches bool
+	// First stops matching a secret at its first match
+	First bool
+	// ExpiringWithin, if set, replaces the search string with finding PEM certificates and JWTs expiring within
+	// this duration, like 30d or 12h
+	ExpiringWithin string
+	// Fuzzy configures the fuzzy match mode
+	Fuzzy FuzzyOptions
+	// Hash replaces the search string with a digest like sha256:<hex>, matched against the hash of every value
+	Hash string
+	// HMACKey is the key for hmac- hash digests
+	HMACKey []byte
+	// IgnoreCase matches with Unicode case folding and NFKC normalization
+	IgnoreCase bool
+	// Invert reports the secrets without any match instead of the matches
+	Invert     bool
+	JSONOutput bool
+	KvVersion  int
+	// LineNumbers matches multi-line values line by line, reporting the line and column of matches
+	LineNumbers bool
+	// MaxDuration stops the crawl after this long, if set
+	MaxDuration time.Duration
+	// MaxResults stops the crawl once this many results are reported, if set
+	MaxResults int
+	// MaxDecodedSize limits the size in bytes of values decoded with DecodeEncoded, defaulting to 1 MiB
+	MaxDecodedSize int
+	// MatchMode is one of substring (default), exact, glob, word, regex or fuzzy
+	MatchMode string
+	// ParseEmbedded searches the key/value pairs of JSON, YAML, INI and dotenv documents stored as values
+	ParseEmbedded bool
+	// Query replaces the search string and search objects with a query evaluated against every key/value pair
+	Query string
+	// Quiet reports nothing, for scripts only checking the exit status, and stops at the first result
+	Quiet         bool
+	SearchObjects []string
+	ShowSecrets   bool
+	// Term replaces the positional search string, e.g. when read from stdin, and is never printed
+	Term string
+	// TermsFile replaces the search string with the terms of this file, searched for in a single crawl
+	TermsFile string
+	Timeout   int
+	// TransitMount enables decrypting transit ciphertext values with the transit engine mounted there
+	TransitMount string
+	// TransitKeys maps secret path prefixes to the transit key used to decrypt their values
+	TransitKeys map[string]string
+	UseRegex    bool
 }
 
 type startPathInfo struct {
@@ -36,10 +129,55 @@ type startPathInfo struct {
 }
 
 type secretMatched struct {
+	Search    string        `json:"search"`
+	FullPath  string        `json:"path"`
+	Key       string        `json:"key"`
+	Value     string        `json:"value"`
+	Decrypted bool          `json:"decrypted,omitempty"`
+	Term      string        `json:"term,omitempty"`
+	Label     string        `json:"label,omitempty"`
+	Distance  *int          `json:"distance,omitempty"`
+	Rule      string        `json:"rule,omitempty"`
+	Expires   string        `json:"expires,omitempty"`
+	Decoded   string        `json:"decoded,omitempty"`
+	Line      int           `json:"line,omitempty"`
+	Column    int           `json:"column,omitempty"`
+	Context   []contextLine `json:"context,omitempty"`
+}
+
+// secretSummary is a secret reported without its matches, with --invert,
+// --count or --files-with-matches
+type secretSummary struct {
 	Search   string `json:"search"`
 	FullPath string `json:"path"`
-	Key      string `json:"key"`
-	Value    string `json:"value"`
+	Count    int    `json:"count,omitempty"`
+}
+
+// searchSummary reports whether a search with --max-results or --max-duration was stopped early
+type searchSummary struct {
+	Search    string `json:"search"`
+	Results   int    `json:"results"`
+	Truncated bool   `json:"truncated"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// contextLine is a line around a line match
+type contextLine struct {
+	Line int    `json:"line"`
+	Text string `json:"text"`
+}
+
+// secretValue is a value of a secret's data converted to a string
+type secretValue struct {
+	// path is the full key path within the secret, like clients[2].secret
+	path string
+	// key is the name of the innermost map key, like secret
+	key   string
+	value string
+	// decrypted is set for transit ciphertexts decrypted before searching
+	decrypted bool
+	// decoded is the chain of decodings applied, like [base64 gzip]
+	decoded []string
 }
 
 // configureToken tries to configure the Vault token on the client.
@@ -92,7 +230,7 @@ func (vc *vaultClient) getKvVersion(path string) (int, error) {
 	for mount := range mounts {
 		if strings.Contains(mount, secret) {
 			version, _ := strconv.Atoi(mounts[mount].Options["version"])
-			if !vc.jsonOutput {
+			if vc.showBanner() {
 				fmt.Printf("Store path %q, version: %v\n", secret, version)
 			}
 			return version, nil
@@ -102,8 +240,9 @@ func (vc *vaultClient) getKvVersion(path string) (int, error) {
 	return 0, errors.New("can't find secret store version")
 }
 
-// VaultKvSearch is the main function
-func VaultKvSearch(args []string, searchObjects []string, showSecrets bool, useRegex bool, crawlingDelay int, kvVersion int, jsonOutput bool, timeoutSeconds int) {
+// newVaultClient creates a Vault client from the environment with the given
+// timeout and a configured token. It exits on failure.
+func newVaultClient(timeoutSeconds int) *vault.Client {
 	config := vault.DefaultConfig()
 	config.Timeout = time.Duration(timeoutSeconds) * time.Second
 
@@ -111,62 +250,218 @@ func VaultKvSearch(args []string, searchObjects []string, showSecrets bool, useR
 	if err != nil {
 		err = fmt.Errorf("failed to create vault client: %w", err)
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(exitStatusError)
 	}
 
 	if err := configureToken(client); err != nil {
-		_, err := fmt.Fprintln(os.Stderr, err)
-		if err != nil {
-			return
-		}
-		os.Exit(1)
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitStatusError)
 	}
 
-	// If the length of positional args is 1, the users didn't specify a search-path and wants to search all available KV stores.
-	var searchString string
-	var searchAllKvStores bool
-	if len(args) == 1 {
-		searchAllKvStores = true
+	return client
+}
+
+// newMatcher compiles pattern with the match settings of opts
+func (opts SearchOptions) newMatcher(pattern string) (Matcher, error) {
+	return newMatcher(resolveMatchMode(opts.MatchMode, opts.UseRegex), pattern, opts.IgnoreCase, opts.Fuzzy)
+}
+
+// VaultKvSearch is the main function. It returns whether anything was found.
+func VaultKvSearch(args []string, opts SearchOptions) bool {
+	var err error
+	client := newVaultClient(opts.Timeout)
+	searchObjects := opts.SearchObjects
+	kvVersion := opts.KvVersion
+
+	// With a query, terms file, term, hash, detect or expiring option, the positional args only hold the optional
+	// search-path. Otherwise the last one is the search string.
+	var searchPath, searchString string
+	if opts.Query != "" || opts.TermsFile != "" || opts.Term != "" || opts.Hash != "" || opts.Detect ||
+		opts.ExpiringWithin != "" {
+		searchString = opts.Term
+		if len(args) == 1 {
+			searchPath = args[0]
+		}
+	} else if len(args) == 1 {
 		searchString = args[0]
 	} else {
-		searchAllKvStores = false
-		searchString = args[1]
+		searchPath, searchString = args[0], args[1]
+	}
+
+	var matcher Matcher
+	var query queryNode
+	var banner string
+	switch {
+	case opts.Query != "":
+		query, err = parseQuery(opts.Query)
+		searchObjects = []string{"query"}
+		banner = fmt.Sprintf("Searching for query '%s'", opts.Query)
+	case opts.TermsFile != "":
+		var terms []searchTerm
+		terms, err = loadTerms(opts.TermsFile)
+		if err == nil {
+			matcher, err = newTermsMatcher(terms, opts.UseRegex, opts.IgnoreCase)
+		}
+		banner = fmt.Sprintf("Searching for %d terms from %s against: %v", len(terms), opts.TermsFile, searchObjects)
+	case opts.Hash != "":
+		matcher, err = newHashMatcher(opts.Hash, opts.HMACKey)
+		// Only values are hashed, and they are never shown
+		searchObjects = []string{"value"}
+		opts.ShowSecrets = false
+		algorithm, _, _ := strings.Cut(opts.Hash, ":")
+		banner = fmt.Sprintf("Searching for %s hash against: %v", strings.ToLower(algorithm), searchObjects)
+	case opts.Detect:
+		var rules []detectionRule
+		rules, err = loadDetectionRules(opts.DetectRules)
+		matcher = newDetectMatcher(rules)
+		searchObjects = []string{"value"}
+		banner = fmt.Sprintf("Searching for credentials with %d detection rules against: %v", len(rules), searchObjects)
+	case opts.ExpiringWithin != "":
+		var within time.Duration
+		within, err = parseDuration(opts.ExpiringWithin)
+		matcher = newExpiryMatcher(within)
+		searchObjects = []string{"value"}
+		banner = fmt.Sprintf("Searching for certificates and tokens expiring within %s against: %v", opts.ExpiringWithin,
+			searchObjects)
+	case opts.Term != "":
+		matcher, err = opts.newMatcher(searchString)
+		if err != nil {
+			// The matcher's error would echo the term
+			err = errors.New("the search term isn't valid for the match mode")
+		}
+		banner = fmt.Sprintf("Searching for substring (hidden) against: %v", searchObjects)
+	default:
+		matcher, err = opts.newMatcher(searchString)
+		banner = fmt.Sprintf("Searching for substring '%s' against: %v", searchString, searchObjects)
+	}
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(exitStatusError)
+	}
+
+	decoders, err := lookupDecoders(opts.Decode)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(exitStatusError)
 	}
 
 	vc := vaultClient{
-		crawlingDelay: crawlingDelay,
-		jsonOutput:    jsonOutput,
-		logical:       client.Logical(),
-		searchObjects: searchObjects,
-		searchString:  searchString,
-		showSecrets:   showSecrets, // pragma: allowlist secret
-		sys:           client.Sys(),
-		useRegex:      useRegex,
-		wg:            sync.WaitGroup{},
-	}
-
-	var startPathsInfo []startPathInfo
-	if searchAllKvStores {
-		startPathsInfo = vc.getAllKvStores()
+		context:          opts.Context,
+		count:            opts.Count,
+		crawlingDelay:    opts.CrawlingDelay,
+		decodeEncoded:    opts.DecodeEncoded,
+		decoders:         decoders,
+		filesWithMatches: opts.FilesWithMatches,
+		first:            opts.First,
+		invert:           opts.Invert,
+		jsonOutput:       opts.JSONOutput,
+		lineNumbers:      opts.LineNumbers || opts.Context > 0,
+		logical:          client.Logical(),
+		matcher:          matcher,
+		maxDecodedSize:   opts.MaxDecodedSize,
+		maxResults:       opts.MaxResults,
+		parseEmbedded:    opts.ParseEmbedded,
+		query:            query,
+		quiet:            opts.Quiet,
+		searchObjects:    searchObjects,
+		searchString:     searchString,
+		showSecrets:      opts.ShowSecrets, // pragma: allowlist secret
+		sys:              client.Sys(),
+		transitKeys:      opts.TransitKeys,
+		transitMount:     strings.Trim(opts.TransitMount, "/"),
+		wg:               sync.WaitGroup{},
+	}
+	if vc.maxDecodedSize <= 0 {
+		vc.maxDecodedSize = defaultMaxDecodedSize
+	}
+	// Like grep -q, quiet searches stop at the first result
+	if vc.quiet && vc.maxResults == 0 {
+		vc.maxResults = 1
+	}
+	if opts.MaxDuration > 0 {
+		vc.ctx, vc.cancel = context.WithTimeout(context.Background(), opts.MaxDuration)
 	} else {
-		if kvVersion == 0 {
-			kvVersion, err = vc.getKvVersion(args[0])
-			if err != nil {
-				fmt.Println(err)
-				os.Exit(1)
-			}
+		vc.ctx, vc.cancel = context.WithCancel(context.Background())
+	}
+	defer vc.cancel()
+	vc.visit = vc.searchSecret
+
+	vc.crawl(vc.startPaths(searchPath, kvVersion), banner)
+
+	summary := searchSummary{Search: "summary", Results: vc.results}
+	switch {
+	case errors.Is(vc.ctx.Err(), context.DeadlineExceeded):
+		summary.Truncated, summary.Reason = true, "max-duration"
+	case errors.Is(vc.ctx.Err(), context.Canceled):
+		summary.Truncated, summary.Reason = true, "max-results"
+	}
+	if (opts.MaxResults > 0 || opts.MaxDuration > 0) && !vc.quiet {
+		vc.showSearchSummary(summary)
+	}
+	// Running out of time before finding anything doesn't mean there is nothing to find
+	if summary.Reason == "max-duration" && vc.results == 0 {
+		os.Exit(exitStatusError)
+	}
+	return vc.results > 0
+}
+
+// showSearchSummary reports the number of results and whether the crawl was
+// stopped early. Text summaries go to stderr, so they don't mix with results.
+func (vc *vaultClient) showSearchSummary(summary searchSummary) {
+	if vc.jsonOutput {
+		summaryJSON, err := json.Marshal(summary)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "can't marshal JSON: %s\n", err)
+			return
+		}
+		fmt.Println(string(summaryJSON))
+		return
+	}
+
+	if summary.Truncated {
+		_, _ = fmt.Fprintf(os.Stderr, "Stopped after %d results, --%s reached. The crawl was truncated\n",
+			summary.Results, summary.Reason)
+	} else {
+		_, _ = fmt.Fprintf(os.Stderr, "Found %d results. The crawl was complete\n", summary.Results)
+	}
+}
+
+// startPaths returns the KV store path to start crawling from. If no
+// search-path was specified, the user wants to crawl all available KV stores.
+func (vc *vaultClient) startPaths(searchPath string, kvVersion int) []startPathInfo {
+	if searchPath == "" {
+		return vc.getAllKvStores()
+	}
+
+	if kvVersion == 0 {
+		var err error
+		kvVersion, err = vc.getKvVersion(searchPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(exitStatusError)
 		}
-		startPathsInfo = append(startPathsInfo, startPathInfo{path: args[0], kvVersion: kvVersion})
 	}
+	return []startPathInfo{{path: searchPath, kvVersion: kvVersion}}
+}
 
+// crawl reads every secret below the start paths and passes it to vc.visit,
+// until vc.ctx, if set, is done
+func (vc *vaultClient) crawl(startPathsInfo []startPathInfo, banner string) {
+	if vc.ctx == nil {
+		vc.ctx = context.Background()
+	}
 	for _, startPathInfo := range startPathsInfo {
+		if vc.ctx.Err() != nil {
+			return
+		}
+
 		// In case the user leaves off the trailing /, let's add it for them
 		if ok := strings.HasSuffix(startPathInfo.path, "/"); !ok {
 			startPathInfo.path += "/"
 		}
 
-		if !vc.jsonOutput {
-			fmt.Printf("Searching for substring '%s' against: %v\n", searchString, searchObjects)
+		if vc.showBanner() {
+			fmt.Println(banner)
 			fmt.Printf("Start path: %s\n", startPathInfo.path)
 		}
 
@@ -174,21 +469,28 @@ func VaultKvSearch(args []string, searchObjects []string, showSecrets bool, useR
 			startPathInfo.path = strings.Replace(startPathInfo.path, "/", "/metadata/", 1)
 		}
 
-		err := vc.readLeafs(startPathInfo.path, searchObjects, startPathInfo.kvVersion)
+		err := vc.readLeafs(startPathInfo.path, startPathInfo.kvVersion)
 		if err != nil {
 			fmt.Println(err.Error())
-			os.Exit(1)
+			os.Exit(exitStatusError)
 		}
 		vc.wg.Wait()
 	}
 }
 
+// showBanner reports whether banners are printed, which is only for text
+// output not meant to be parsed, like the paths of --files-with-matches
+func (vc *vaultClient) showBanner() bool {
+	return !vc.jsonOutput && !vc.quiet && !vc.count && !vc.filesWithMatches
+}
+
 func (vc *vaultClient) getAllKvStores() []startPathInfo {
 	var info []startPathInfo
 
 	mountPoints, err := vc.sys.ListMounts()
 	if err != nil {
-		log.Fatalf("Could not get a list of mounts: %v", err)
+		log.Printf("Could not get a list of mounts: %v", err)
+		os.Exit(exitStatusError)
 	}
 
 	// Loop through all mountpoints and save only those that are of types kv or generic (old vault KVv1)
@@ -202,33 +504,118 @@ func (vc *vaultClient) getAllKvStores() []startPathInfo {
 	return info
 }
 
-func (vc *vaultClient) secretMatch(dirEntry string, fullPath string, searchObject string, key string, value string) {
-	search := map[string]string{"path": dirEntry, "key": key, "value": value}
-	term := search[searchObject]
-	found := false
+// secretMatch returns the matches of a value for the search object
+func (vc *vaultClient) secretMatch(dirEntry string, fullPath string, searchObject string, v secretValue) []secretMatched {
+	if vc.lineNumbers && searchObject == "value" && strings.Contains(v.value, "\n") {
+		return vc.lineMatch(fullPath, v)
+	}
 
-	if vc.useRegex {
-		found, _ = regexp.MatchString(vc.searchString, term)
-		if !found && searchObject == "path" {
-			found, _ = regexp.MatchString(vc.searchString, fullPath)
-		}
+	var hits []Hit
+	if searchObject == "query" {
+		hits = hitIf(vc.query.eval(queryPair{dirEntry, fullPath, v.path, v.key, v.value}))
 	} else {
-		found = strings.Contains(term, vc.searchString)
-		if !found && searchObject == "path" {
-			found = strings.Contains(fullPath, vc.searchString)
+		search := map[string]string{"path": dirEntry, "key": v.path, "value": v.value}
+		term := search[searchObject]
+		hits = vc.matcher.Match(term)
+		if hits == nil && searchObject == "path" {
+			hits = vc.matcher.Match(fullPath)
+		}
+		// A key matches on either its full path or its name
+		if hits == nil && searchObject == "key" && v.key != v.path {
+			hits = vc.matcher.Match(v.key)
+		}
+	}
+
+	var matches []secretMatched
+	for _, hit := range hits {
+		matches = append(matches, secretMatched{
+			Search:    searchObject,
+			FullPath:  fullPath,
+			Key:       v.path,
+			Value:     v.value,
+			Decrypted: v.decrypted,
+			Term:      hit.Term,
+			Label:     hit.Label,
+			Distance:  hit.Distance,
+			Rule:      hit.Rule,
+			Expires:   hit.Expires,
+			Decoded:   strings.Join(v.decoded, "+"),
+		})
+	}
+	return matches
+}
+
+// lineMatch matches a multi-line value line by line, reporting every matching
+// line with its line number, the column of the match if known, and the
+// surrounding lines of context
+func (vc *vaultClient) lineMatch(fullPath string, v secretValue) []secretMatched {
+	lines := strings.Split(v.value, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+
+	var matches []secretMatched
+	for i, line := range lines {
+		for _, hit := range vc.matcher.Match(line) {
+			match := secretMatched{
+				Search:    "value",
+				FullPath:  fullPath,
+				Key:       v.path,
+				Value:     line,
+				Decrypted: v.decrypted,
+				Term:      hit.Term,
+				Label:     hit.Label,
+				Distance:  hit.Distance,
+				Rule:      hit.Rule,
+				Expires:   hit.Expires,
+				Decoded:   strings.Join(v.decoded, "+"),
+				Line:      i + 1,
+				Column:    hit.Column,
+			}
+			if vc.context > 0 {
+				for j := max(i-vc.context, 0); j <= min(i+vc.context, len(lines)-1); j++ {
+					match.Context = append(match.Context, contextLine{j + 1, lines[j]})
+				}
+			}
+			matches = append(matches, match)
 		}
 	}
+	return matches
+}
 
-	if found {
-		match := secretMatched{searchObject, fullPath, key, value}
-		vc.showMatch(match)
+// maskedKeyPattern matches the key and separator of lines like "key = value" or "key: value"
+var maskedKeyPattern = regexp.MustCompile(`^\s*[A-Za-z_][A-Za-z0-9_.-]{0,63}\s*[=:]`)
+
+// maskLine hides the value of a line of context. Lines like "key = value" or
+// "key: value" keep their key, so the context still shows where a match is.
+// Anything else, like a base64 value whose padding looks like a separator, is
+// masked entirely.
+func maskLine(line string) string {
+	if strings.TrimSpace(line) == "" {
+		return line
+	}
+	if key := maskedKeyPattern.FindString(line); key != "" {
+		value := strings.TrimSpace(line[len(key):])
+		if strings.Trim(value, "=") != "" {
+			return key + " ********"
+		}
 	}
+	return "********"
 }
 
 func (vc *vaultClient) showMatch(secret secretMatched) {
+	if !vc.showSecrets {
+		for i, line := range secret.Context {
+			secret.Context[i].Text = maskLine(line.Text)
+		}
+	}
+
 	if vc.jsonOutput {
 		if !vc.showSecrets {
 			secret.Value = "obfuscated"
+			if secret.Term != "" {
+				secret.Term = "obfuscated"
+			}
 		}
 		secretJSON, err := json.Marshal(secret)
 		if err != nil {
@@ -239,53 +626,233 @@ func (vc *vaultClient) showMatch(secret secretMatched) {
 		}
 		fmt.Println(string(secretJSON))
 	} else {
+		// Matches are shown from concurrent crawling goroutines, so every block is written at once
+		var b strings.Builder
 		title := cases.Title(language.English)
+		fmt.Fprintf(&b, "%s match:\n\tSecret: %s\n\tKey: %s\n", title.String(secret.Search), secret.FullPath,
+			secret.Key)
+		if secret.Column > 0 {
+			fmt.Fprintf(&b, "\tLine: %d, Column: %d\n", secret.Line, secret.Column)
+		} else if secret.Line > 0 {
+			fmt.Fprintf(&b, "\tLine: %d\n", secret.Line)
+		}
 		if vc.showSecrets {
-			fmt.Printf("%s match:\n\tSecret: %s\n\tKey: %s\n\tValue: %s\n\n", title.String(secret.Search), secret.FullPath, secret.Key, secret.Value)
-		} else {
-			fmt.Printf("%s match:\n\tSecret: %s\n\tKey: %s\n\n", title.String(secret.Search), secret.FullPath, secret.Key)
+			fmt.Fprintf(&b, "\tValue: %s\n", secret.Value)
+			if secret.Term != "" {
+				fmt.Fprintf(&b, "\tTerm: %s\n", secret.Term)
+			}
+		}
+		if secret.Label != "" {
+			fmt.Fprintf(&b, "\tLabel: %s\n", secret.Label)
+		}
+		if secret.Rule != "" {
+			fmt.Fprintf(&b, "\tRule: %s\n", secret.Rule)
+		}
+		if secret.Expires != "" {
+			fmt.Fprintf(&b, "\tExpires: %s\n", secret.Expires)
 		}
+		if secret.Distance != nil {
+			fmt.Fprintf(&b, "\tDistance: %d\n", *secret.Distance)
+		}
+		if secret.Decrypted {
+			fmt.Fprintf(&b, "\tDecrypted: %s\n", vc.transitMount)
+		}
+		if secret.Decoded != "" {
+			fmt.Fprintf(&b, "\tDecoded: %s\n", secret.Decoded)
+		}
+		if len(secret.Context) > 0 {
+			b.WriteString("\tContext:\n")
+			for _, line := range secret.Context {
+				// Like grep, the matching line is marked with a colon and the others with a dash
+				separator := "-"
+				if line.Line == secret.Line {
+					separator = ":"
+				}
+				fmt.Fprintf(&b, "\t\t%d%s %s\n", line.Line, separator, line.Text)
+			}
+		}
+		b.WriteString("\n")
+		fmt.Print(b.String())
 	}
 }
 
-func (vc *vaultClient) digDeeper(version int, data map[string]interface{}, dirEntry string, fullPath string, searchObject string) (key string, value string) {
-	var valueStringType string
-
-	for key, value := range data {
-		if version > 1 && key == "metadata" {
-			continue
-		}
-		switch v := value.(type) {
-		// Convert types to strings
-		case string:
-			valueStringType = value.(string)
-		case json.Number:
-			valueStringType = v.String()
-		case bool:
-			valueStringType = strconv.FormatBool(v)
-		case map[string]interface{}:
-			// Recurse into nested map, but don't return immediately
-			// Continue processing other keys at this level
-			vc.digDeeper(version, v, dirEntry, fullPath, searchObject)
-			continue
-		// Needed when start from root of the store
-		case []interface{}:
-		case nil:
-		default:
-			fmt.Printf("I don't know what %T is\n", v)
-			os.Exit(1)
-		}
-		// Search matches
-		vc.secretMatch(dirEntry, fullPath, searchObject, key, valueStringType)
-	}
-
-	return key, valueStringType
+// searchDerived searches the fields decoded from a structured value and the
+// pairs of a document embedded in it as virtual keys, like
+// tls.crt#x509.subject or config#database.password. They don't change the
+// path, so they aren't searched for path matches. With decodeEncoded, the
+// decoded text of base64 values is searched under the same key.
+func (vc *vaultClient) searchDerived(dirEntry string, fullPath string, searchObject string, v secretValue) []secretMatched {
+	if searchObject == "path" {
+		return nil
+	}
+	var matches []secretMatched
+
+	virtual := func(name string, value string) secretValue {
+		key := v.path + "#" + name
+		return secretValue{key, key, value, v.decrypted, v.decoded}
+	}
+
+	for _, field := range decodeValue(vc.decoders, v.value) {
+		matches = append(matches, vc.secretMatch(dirEntry, fullPath, searchObject, virtual(field.name, field.value))...)
+	}
+
+	if vc.parseEmbedded {
+		for _, field := range parseEmbedded(v.value) {
+			embedded := virtual(field.name, field.value)
+			matches = append(matches, vc.secretMatch(dirEntry, fullPath, searchObject, embedded)...)
+			// Embedded values can hold certificates or documents themselves
+			matches = append(matches, vc.searchDerived(dirEntry, fullPath, searchObject, embedded)...)
+		}
+	}
+
+	if vc.decodeEncoded && len(v.decoded) < maxDecodeSteps {
+		steps, text, ok, err := unwrapValue(v.value, vc.maxDecodedSize)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "!!Warning!! skipping key %s of %s: %s\n", v.path, fullPath, err)
+			return matches
+		}
+		if !ok {
+			return matches
+		}
+		decoded := v
+		decoded.value = text
+		decoded.decoded = append(append([]string(nil), v.decoded...), steps...)
+		matches = append(matches, vc.secretMatch(dirEntry, fullPath, searchObject, decoded)...)
+		// Decoded text can be encoded again, or hold certificates or documents
+		matches = append(matches, vc.searchDerived(dirEntry, fullPath, searchObject, decoded)...)
+	}
+	return matches
 }
 
-func (vc *vaultClient) readLeafs(path string, searchObjects []string, version int) error {
-	pathList, err := vc.logical.List(path)
+// walkValues calls fn with every value of a secret's data converted to a
+// string, recursing into nested maps and lists. For KV v2, only the data of
+// the secret is walked, not its metadata.
+func walkValues(version int, data map[string]interface{}, fn func(v secretValue)) {
+	if version > 1 {
+		data, _ = data["data"].(map[string]interface{})
+	}
+	for _, key := range sortedKeys(data) {
+		walkValue(key, key, data[key], fn)
+	}
+}
+
+func walkValue(path string, key string, value interface{}, fn func(v secretValue)) {
+	switch v := value.(type) {
+	// Convert types to strings
+	case string:
+		fn(secretValue{path: path, key: key, value: v})
+	case transitPlaintext:
+		fn(secretValue{path: path, key: key, value: string(v), decrypted: true})
+	case json.Number:
+		fn(secretValue{path: path, key: key, value: v.String()})
+	case bool:
+		fn(secretValue{path: path, key: key, value: strconv.FormatBool(v)})
+	case map[string]interface{}:
+		for _, child := range sortedKeys(v) {
+			walkValue(path+"."+child, child, v[child], fn)
+		}
+	case []interface{}:
+		// List items keep the name of the list as their key
+		for i, item := range v {
+			walkValue(fmt.Sprintf("%s[%d]", path, i), key, item, fn)
+		}
+	case nil:
+	default:
+		fmt.Printf("I don't know what %T is\n", v)
+		os.Exit(exitStatusError)
+	}
+}
+
+// searchSecret matches a secret's data against every search object and
+// reports the matches, their count or the secret's path. With invert, it
+// instead reports the secret if nothing in it matches.
+func (vc *vaultClient) searchSecret(dirEntry string, fullPath string, version int, data map[string]interface{}) {
+	var matches []secretMatched
+	for _, searchObject := range vc.searchObjects {
+		walkValues(version, data, func(v secretValue) {
+			if vc.first && len(matches) > 0 {
+				return
+			}
+			matches = append(matches, vc.secretMatch(dirEntry, fullPath, searchObject, v)...)
+			matches = append(matches, vc.searchDerived(dirEntry, fullPath, searchObject, v)...)
+		})
+	}
+	if vc.first && len(matches) > 1 {
+		matches = matches[:1]
+	}
+
+	switch {
+	case vc.invert:
+		if len(matches) == 0 {
+			vc.report(1, func(int) { vc.showSummary(secretSummary{Search: "invert", FullPath: fullPath}) })
+		}
+	case len(matches) == 0:
+	case vc.count:
+		vc.report(1, func(int) { vc.showSummary(secretSummary{"count", fullPath, len(matches)}) })
+	case vc.filesWithMatches:
+		vc.report(1, func(int) { vc.showSummary(secretSummary{Search: "files-with-matches", FullPath: fullPath}) })
+	default:
+		vc.report(len(matches), func(limit int) {
+			for _, match := range matches[:limit] {
+				vc.showMatch(match)
+			}
+		})
+	}
+}
+
+// report counts results and shows them unless quiet. With maxResults, only
+// the results up to it are shown, passing their number to show, and the crawl
+// is stopped once it is reached.
+func (vc *vaultClient) report(results int, show func(limit int)) {
+	vc.mu.Lock()
+	if vc.maxResults > 0 {
+		results = min(results, vc.maxResults-vc.results)
+	}
+	if results <= 0 {
+		vc.mu.Unlock()
+		return
+	}
+	vc.results += results
+	if vc.maxResults > 0 && vc.results >= vc.maxResults && vc.cancel != nil {
+		vc.cancel()
+	}
+	vc.mu.Unlock()
+
+	if !vc.quiet {
+		show(results)
+	}
+}
+
+// showSummary reports a secret without its matches
+func (vc *vaultClient) showSummary(secret secretSummary) {
+	if vc.jsonOutput {
+		secretJSON, err := json.Marshal(secret)
+		if err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "can't marshal JSON: %s\n", err)
+			return
+		}
+		fmt.Println(string(secretJSON))
+		return
+	}
+
+	switch secret.Search {
+	case "count":
+		fmt.Printf("%s:%d\n", secret.FullPath, secret.Count)
+	case "files-with-matches":
+		fmt.Println(secret.FullPath)
+	default:
+		fmt.Printf("No match:\n\tSecret: %s\n\n", secret.FullPath)
+	}
+}
+
+func (vc *vaultClient) readLeafs(path string, version int) error {
+	pathList, err := vc.logical.ListWithContext(vc.ctx, path)
 	if err != nil {
-		return fmt.Errorf("failed to list: %s\n%s", vc.searchString, err)
+		// The crawl was stopped early
+		if vc.ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("failed to list: %s\n%s", path, err)
 	}
 
 	if pathList == nil {
@@ -301,6 +868,10 @@ func (vc *vaultClient) readLeafs(path string, searchObjects []string, version in
 	}
 
 	for _, x := range pathList.Data["keys"].([]interface{}) {
+		if vc.ctx.Err() != nil {
+			return nil
+		}
+
 		// Slow down a little the crawling
 		time.Sleep(time.Duration(vc.crawlingDelay) * time.Millisecond)
 
@@ -310,30 +881,46 @@ func (vc *vaultClient) readLeafs(path string, searchObjects []string, version in
 			vc.wg.Add(1)
 			go func() {
 				defer vc.wg.Done()
-				err := vc.readLeafs(fullPath, searchObjects, version)
+				err := vc.readLeafs(fullPath, version)
 				if err != nil {
 					fmt.Println(err)
-					os.Exit(1)
+					os.Exit(exitStatusError)
 				}
 			}()
 
 		} else {
-			if version > 1 {
-				fullPath = strings.Replace(fullPath, "/metadata/", "/data/", 1)
+			readPath := fullPath
+			if version > 1 && !vc.metadataOnly {
+				readPath = strings.Replace(fullPath, "/metadata/", "/data/", 1)
 			}
 
-			secretInfo, err := vc.logical.Read(fullPath)
+			secretInfo, err := vc.logical.ReadWithContext(vc.ctx, readPath)
 			if err != nil {
+				if vc.ctx.Err() != nil {
+					return nil
+				}
 				fmt.Println(err)
-				os.Exit(1)
+				os.Exit(exitStatusError)
 			}
 
-			for _, searchObject := range searchObjects {
-				if version > 1 {
-					fullPath = strings.Replace(fullPath, "/data", "", 1)
-				}
-				vc.digDeeper(version, secretInfo.Data, dirEntry, fullPath, searchObject)
+			if version > 1 {
+				fullPath = strings.Replace(fullPath, "/metadata", "", 1)
+			}
+
+			// Secrets can disappear between listing and reading them
+			if secretInfo == nil {
+				continue
+			}
+			// Reading a KV v2 secret whose latest version is deleted or destroyed returns only its metadata
+			if version > 1 && !vc.metadataOnly && secretInfo.Data["data"] == nil {
+				continue
 			}
+
+			if vc.transitMount != "" {
+				vc.decryptTransitValues(fullPath, secretInfo.Data)
+			}
+
+			vc.visit(dirEntry, fullPath, version, secretInfo.Data)
 		}
 	}
 	return nil
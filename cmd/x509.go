@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// parsePEMCertificates returns the certificates of the PEM CERTIFICATE blocks in value, skipping invalid ones
+func parsePEMCertificates(value string) []*x509.Certificate {
+	if !strings.Contains(value, "-----BEGIN CERTIFICATE-----") {
+		return nil
+	}
+
+	var certs []*x509.Certificate
+	rest := []byte(value)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return certs
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		certs = append(certs, cert)
+	}
+}
+
+// decodeX509 exposes the subject, SANs, issuer, serial number, SHA-256
+// fingerprint and expiry of PEM certificates. Certificates after the first one
+// of a chain are numbered, like x509[1].subject.
+func decodeX509(value string) []decodedField {
+	var fields []decodedField
+	for i, cert := range parsePEMCertificates(value) {
+		prefix := "x509"
+		if i > 0 {
+			prefix = fmt.Sprintf("x509[%d]", i)
+		}
+		field := func(name string, value string) {
+			fields = append(fields, decodedField{prefix + "." + name, value})
+		}
+
+		field("subject", cert.Subject.String())
+		for _, san := range certificateSANs(cert) {
+			field("san", san)
+		}
+		field("issuer", cert.Issuer.String())
+		field("serial", cert.SerialNumber.Text(16))
+		fingerprint := sha256.Sum256(cert.Raw)
+		field("fingerprint", hex.EncodeToString(fingerprint[:]))
+		field("not_after", cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return fields
+}
+
+func certificateSANs(cert *x509.Certificate) []string {
+	sans := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return sans
+}
+
+// expiryMatcher finds PEM certificates and JWTs expiring before a deadline, including expired ones
+type expiryMatcher struct {
+	deadline time.Time
+}
+
+func newExpiryMatcher(within time.Duration) Matcher {
+	return expiryMatcher{time.Now().Add(within)}
+}
+
+func (m expiryMatcher) Match(s string) []Hit {
+	var hits []Hit
+	for _, cert := range parsePEMCertificates(s) {
+		if cert.NotAfter.Before(m.deadline) {
+			hits = append(hits, Hit{Label: cert.Subject.String(), Expires: cert.NotAfter.UTC().Format(time.RFC3339)})
+		}
+	}
+	for _, token := range parseJWTs(s) {
+		if exp, ok := token.expiry(); ok && exp.Before(m.deadline) {
+			hits = append(hits, Hit{Label: token.label(), Expires: exp.Format(time.RFC3339)})
+		}
+	}
+	return hits
+}
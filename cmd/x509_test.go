@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testCertificate returns a self-signed PEM certificate for commonName and its DER bytes
+func testCertificate(t *testing.T, commonName string, notAfter time.Time) (string, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(0xbeef),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName, "www." + commonName},
+		IPAddresses:  []net.IP{net.ParseIP("10.0.0.1")},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), der
+}
+
+func TestDecodeX509(t *testing.T) {
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	cert, der := testCertificate(t, "api.example.com", notAfter)
+	chain, _ := testCertificate(t, "ca.example.com", notAfter)
+	fingerprint := sha256.Sum256(der)
+
+	expected := []decodedField{
+		{"x509.subject", "CN=api.example.com"},
+		{"x509.san", "api.example.com"},
+		{"x509.san", "www.api.example.com"},
+		{"x509.san", "10.0.0.1"},
+		{"x509.issuer", "CN=api.example.com"},
+		{"x509.serial", "beef"},
+		{"x509.fingerprint", hex.EncodeToString(fingerprint[:])},
+		{"x509.not_after", "2030-01-02T03:04:05Z"},
+	}
+
+	fields := decodeX509(cert + chain)
+	if len(fields) != 2*len(expected) {
+		t.Fatalf("Expected %d fields, but got %v", 2*len(expected), fields)
+	}
+	if !reflect.DeepEqual(fields[:len(expected)], expected) {
+		t.Errorf("Expected fields %v, but got %v", expected, fields[:len(expected)])
+	}
+	if fields[len(expected)].name != "x509[1].subject" || fields[len(expected)].value != "CN=ca.example.com" {
+		t.Errorf("Expected x509[1].subject of the second certificate, but got %v", fields[len(expected)])
+	}
+
+	for _, value := range []string{"", "not a certificate", "-----BEGIN CERTIFICATE-----\ngarbage\n-----END CERTIFICATE-----"} {
+		if fields := decodeX509(value); fields != nil {
+			t.Errorf("Expected no fields for '%s', but got %v", value, fields)
+		}
+	}
+}
+
+func TestExpiryMatcher(t *testing.T) {
+	now := time.Now()
+	expired, _ := testCertificate(t, "expired.example.com", now.Add(-time.Hour))
+	soon, _ := testCertificate(t, "soon.example.com", now.Add(10*24*time.Hour))
+	later, _ := testCertificate(t, "later.example.com", now.Add(90*24*time.Hour))
+
+	matcher := newExpiryMatcher(30 * 24 * time.Hour)
+
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{expired, []string{"CN=expired.example.com"}},
+		{soon, []string{"CN=soon.example.com"}},
+		{later, nil},
+		{later + soon, []string{"CN=soon.example.com"}},
+		{"not a certificate", nil},
+	}
+
+	for _, tt := range tests {
+		var actual []string
+		for _, hit := range matcher.Match(tt.value) {
+			if hit.Expires == "" {
+				t.Errorf("Expected the expiry of %s to be set", hit.Label)
+			}
+			actual = append(actual, hit.Label)
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("Expected expiring certificates %v, but got %v", tt.expected, actual)
+		}
+	}
+}